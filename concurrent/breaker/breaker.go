@@ -0,0 +1,302 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrOpen            = errors.New("circuit breaker is open")
+	ErrTooManyRequests = errors.New("circuit breaker is half-open and has too many requests")
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type Config struct {
+	Name string
+
+	// WindowSize is the number of the latest calls the rates are calculated from, default 100.
+	WindowSize int
+	// MinimumCalls is the number of calls required before the rates are evaluated, default 10.
+	MinimumCalls int
+	// FailureRateThreshold opens the breaker when the failure rate in the window
+	// reaches it, 0 disables it.
+	FailureRateThreshold float64
+	// ConsecutiveFailures opens the breaker after so many failures in a row, 0 disables it.
+	ConsecutiveFailures int
+	// SlowCallDuration is the duration above which a call is slow, 0 disables slow call detection.
+	SlowCallDuration time.Duration
+	// SlowCallRateThreshold opens the breaker when the slow call rate in the window reaches it.
+	SlowCallRateThreshold float64
+
+	// OpenTimeout is how long the breaker stays open before it becomes half-open, default 60s.
+	OpenTimeout time.Duration
+	// HalfOpenCalls is the number of trial calls permitted in half-open state, default 1.
+	// The breaker closes when all of them succeeded and opens again on any failure.
+	HalfOpenCalls int
+
+	// IsFailure classifies the error of a call, default every non-nil error
+	// except context.Canceled is a failure.
+	IsFailure func(error) bool
+	// OnStateChange is called with the lock of the breaker released.
+	OnStateChange func(name string, from, to State)
+}
+
+type outcome struct {
+	failure bool
+	slow    bool
+}
+
+// Breaker is a circuit breaker with closed, open and half-open states.
+type Breaker struct {
+	config Config
+
+	lock        sync.Mutex
+	state       State
+	openedAt    time.Time
+	window      []outcome
+	next        int
+	failures    int
+	slows       int
+	consecutive int
+	halfOpen    int // calls admitted in half-open state
+	succeeded   int // successful calls in half-open state
+	generation  uint64
+}
+
+func New(config Config) *Breaker {
+	if config.WindowSize <= 0 {
+		config.WindowSize = 100
+	}
+	if config.MinimumCalls <= 0 {
+		config.MinimumCalls = 10
+	}
+	if config.MinimumCalls > config.WindowSize {
+		config.MinimumCalls = config.WindowSize
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 60 * time.Second
+	}
+	if config.HalfOpenCalls <= 0 {
+		config.HalfOpenCalls = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = func(err error) bool {
+			return err != nil && !errors.Is(err, context.Canceled)
+		}
+	}
+
+	return &Breaker{config: config}
+}
+
+func (b *Breaker) Name() string {
+	return b.config.Name
+}
+
+func (b *Breaker) State() State {
+	b.lock.Lock()
+	state, changed := b.currentState(time.Now())
+	b.lock.Unlock()
+
+	b.notify(changed)
+	return state
+}
+
+// Execute runs fn if the breaker permits it and records its result.
+func (b *Breaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			done(fmt.Errorf("panic: %v", r))
+			panic(r)
+		}
+	}()
+
+	err = fn(ctx)
+	done(err)
+	return err
+}
+
+// Allow checks whether a call is permitted, the caller must report the
+// result of the call by done.
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.lock.Lock()
+
+	now := time.Now()
+	state, changed := b.currentState(now)
+	switch state {
+	case StateOpen:
+		b.lock.Unlock()
+		b.notify(changed)
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.halfOpen >= b.config.HalfOpenCalls {
+			b.lock.Unlock()
+			b.notify(changed)
+			return nil, ErrTooManyRequests
+		}
+		b.halfOpen++
+	}
+	generation := b.generation
+	b.lock.Unlock()
+	b.notify(changed)
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			b.record(generation, now, err)
+		})
+	}, nil
+}
+
+func (b *Breaker) record(generation uint64, start time.Time, err error) {
+	now := time.Now()
+
+	b.lock.Lock()
+	if generation != b.generation {
+		// the state has changed since the call was admitted
+		b.lock.Unlock()
+		return
+	}
+
+	o := outcome{
+		failure: b.config.IsFailure(err),
+		slow:    b.config.SlowCallDuration > 0 && now.Sub(start) > b.config.SlowCallDuration,
+	}
+
+	var changed []State
+	if b.state == StateHalfOpen {
+		if o.failure || o.slow {
+			changed = b.setState(StateOpen, now)
+		} else {
+			b.succeeded++
+			if b.succeeded >= b.config.HalfOpenCalls {
+				changed = b.setState(StateClosed, now)
+			}
+		}
+	} else {
+		b.push(o)
+		if b.shouldOpen() {
+			changed = b.setState(StateOpen, now)
+		}
+	}
+	b.lock.Unlock()
+
+	b.notify(changed)
+}
+
+func (b *Breaker) push(o outcome) {
+	if len(b.window) < b.config.WindowSize {
+		b.window = append(b.window, o)
+	} else {
+		old := b.window[b.next]
+		if old.failure {
+			b.failures--
+		}
+		if old.slow {
+			b.slows--
+		}
+		b.window[b.next] = o
+		b.next = (b.next + 1) % b.config.WindowSize
+	}
+
+	if o.failure {
+		b.failures++
+		b.consecutive++
+	} else {
+		b.consecutive = 0
+	}
+	if o.slow {
+		b.slows++
+	}
+}
+
+func (b *Breaker) shouldOpen() bool {
+	if b.config.ConsecutiveFailures > 0 && b.consecutive >= b.config.ConsecutiveFailures {
+		return true
+	}
+
+	n := len(b.window)
+	if n < b.config.MinimumCalls {
+		return false
+	}
+	if b.config.FailureRateThreshold > 0 &&
+		float64(b.failures)/float64(n) >= b.config.FailureRateThreshold {
+		return true
+	}
+	if b.config.SlowCallDuration > 0 && b.config.SlowCallRateThreshold > 0 &&
+		float64(b.slows)/float64(n) >= b.config.SlowCallRateThreshold {
+		return true
+	}
+	return false
+}
+
+// currentState moves an open breaker to half-open after the timeout,
+// it must be called with the lock held.
+func (b *Breaker) currentState(now time.Time) (State, []State) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.config.OpenTimeout {
+		return StateHalfOpen, b.setState(StateHalfOpen, now)
+	}
+	return b.state, nil
+}
+
+// setState resets the counters of the new state and returns the transition
+// to be notified after the lock is released.
+func (b *Breaker) setState(state State, now time.Time) []State {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.halfOpen = 0
+	b.succeeded = 0
+
+	switch state {
+	case StateOpen:
+		b.openedAt = now
+	case StateClosed:
+		b.window = b.window[:0]
+		b.next = 0
+		b.failures = 0
+		b.slows = 0
+		b.consecutive = 0
+	}
+
+	if from == state {
+		return nil
+	}
+	return []State{from, state}
+}
+
+func (b *Breaker) notify(changed []State) {
+	if len(changed) == 2 && b.config.OnStateChange != nil {
+		b.config.OnStateChange(b.config.Name, changed[0], changed[1])
+	}
+}
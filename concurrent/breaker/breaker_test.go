@@ -0,0 +1,132 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errBackend = errors.New("backend error")
+
+func fail(ctx context.Context) error    { return errBackend }
+func succeed(ctx context.Context) error { return nil }
+
+func TestBreakerConsecutiveFailures(t *testing.T) {
+	var lock sync.Mutex
+	var transitions []string
+	b := New(Config{
+		Name:                "hbase",
+		ConsecutiveFailures: 3,
+		OpenTimeout:         20 * time.Millisecond,
+		HalfOpenCalls:       2,
+		OnStateChange: func(name string, from, to State) {
+			lock.Lock()
+			transitions = append(transitions, name+":"+from.String()+"->"+to.String())
+			lock.Unlock()
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, errBackend, b.Execute(context.Background(), fail))
+	}
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, ErrOpen, b.Execute(context.Background(), succeed))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	done1, err := b.Allow()
+	assert.Nil(t, err)
+	done2, err := b.Allow()
+	assert.Nil(t, err)
+	_, err = b.Allow()
+	assert.Equal(t, ErrTooManyRequests, err)
+	done1(nil)
+	done2(nil)
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.Equal(t, []string{
+		"hbase:closed->open",
+		"hbase:open->half-open",
+		"hbase:half-open->closed",
+	}, transitions)
+}
+
+func TestBreakerFailureRate(t *testing.T) {
+	b := New(Config{
+		WindowSize:           10,
+		MinimumCalls:         10,
+		FailureRateThreshold: 0.5,
+		OpenTimeout:          20 * time.Millisecond,
+	})
+
+	for i := 0; i < 9; i++ {
+		if i%2 == 0 {
+			_ = b.Execute(context.Background(), fail)
+		} else {
+			_ = b.Execute(context.Background(), succeed)
+		}
+	}
+	assert.Equal(t, StateClosed, b.State())
+
+	_ = b.Execute(context.Background(), succeed)
+	assert.Equal(t, StateOpen, b.State())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, errBackend, b.Execute(context.Background(), fail))
+	assert.Equal(t, StateOpen, b.State())
+}
+
+func TestBreakerSlowCalls(t *testing.T) {
+	b := New(Config{
+		WindowSize:            4,
+		MinimumCalls:          4,
+		SlowCallDuration:      time.Millisecond,
+		SlowCallRateThreshold: 0.5,
+	})
+
+	slow := func(ctx context.Context) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+	_ = b.Execute(context.Background(), succeed)
+	_ = b.Execute(context.Background(), succeed)
+	_ = b.Execute(context.Background(), slow)
+	assert.Equal(t, StateClosed, b.State())
+	_ = b.Execute(context.Background(), slow)
+	assert.Equal(t, StateOpen, b.State())
+}
+
+func TestBulkhead(t *testing.T) {
+	b := NewBulkhead("redis", 2, 0)
+
+	release1, err := b.Acquire(context.Background())
+	assert.Nil(t, err)
+	release2, err := b.Acquire(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, b.InUse())
+
+	_, err = b.Acquire(context.Background())
+	assert.Equal(t, ErrBulkheadFull, err)
+
+	release1()
+	release1()
+	assert.Equal(t, 1, b.InUse())
+
+	assert.Nil(t, b.Execute(context.Background(), succeed))
+	release2()
+	assert.Equal(t, 0, b.InUse())
+
+	waiting := NewBulkhead("hbase", 1, time.Second)
+	release, err := waiting.Acquire(context.Background())
+	assert.Nil(t, err)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+	assert.Nil(t, waiting.Execute(context.Background(), succeed))
+}
@@ -0,0 +1,87 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrBulkheadFull = errors.New("bulkhead is full")
+
+// Bulkhead caps the number of concurrent calls to a dependency.
+type Bulkhead struct {
+	name    string
+	sem     chan struct{}
+	maxWait time.Duration
+}
+
+// NewBulkhead creates a bulkhead permitting max concurrent calls, a call
+// waits at most maxWait for a permit, 0 means that it does not wait.
+func NewBulkhead(name string, max int, maxWait time.Duration) *Bulkhead {
+	if max <= 0 {
+		max = 1
+	}
+	return &Bulkhead{
+		name:    name,
+		sem:     make(chan struct{}, max),
+		maxWait: maxWait,
+	}
+}
+
+func (b *Bulkhead) Name() string {
+	return b.name
+}
+
+// InUse returns the number of calls being executed.
+func (b *Bulkhead) InUse() int {
+	return len(b.sem)
+}
+
+// Acquire takes a permit, the caller must release it after the call.
+func (b *Bulkhead) Acquire(ctx context.Context) (release func(), err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() { <-b.sem })
+	}
+
+	select {
+	case b.sem <- struct{}{}:
+		return release, nil
+	default:
+		if b.maxWait <= 0 {
+			return nil, ErrBulkheadFull
+		}
+	}
+
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+
+	select {
+	case b.sem <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrBulkheadFull
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Execute runs fn if a permit can be acquired.
+func (b *Bulkhead) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn(ctx)
+}
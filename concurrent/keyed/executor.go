@@ -0,0 +1,255 @@
+package keyed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/shima-park/tools/concurrent/pool"
+)
+
+var ErrClosed = errors.New("executor is closed")
+
+// Job is processed after every job of the same key submitted before it.
+type Job struct {
+	Key       string
+	Partition int32
+	Offset    int64 // offsets of a partition must be submitted in non-decreasing order
+	Fn        func(ctx context.Context) error
+}
+
+type lane struct {
+	ch   chan *Job
+	done chan struct{}
+	// after are the previous lanes still draining, their jobs run first
+	after []*lane
+}
+
+// Executor hashes the key of every job onto one of the lanes, a lane is
+// served by one worker of an AdjustablePool so jobs of the same key run
+// serially while different keys run in parallel.
+type Executor struct {
+	pool      *pool.AdjustablePool
+	laneSize  int
+	errHandle func(Job, error)
+
+	lock   sync.RWMutex // held for writing while the lanes are replaced
+	lanes  []*lane
+	closed bool
+
+	offsetLock sync.Mutex
+	offsets    map[int32]*offsetTracker
+}
+
+// New creates an executor of n lanes buffering up to laneSize jobs each,
+// errHandle is called with the jobs that failed, it may be nil. The offset
+// of a failed job is not committed until the job is submitted again.
+func New(n, laneSize int, errHandle func(Job, error)) (*Executor, error) {
+	if n <= 0 {
+		return nil, errors.New("number of lanes must be positive")
+	}
+	if laneSize < 0 {
+		laneSize = 0
+	}
+
+	e := &Executor{
+		laneSize:  laneSize,
+		errHandle: errHandle,
+		offsets:   make(map[int32]*offsetTracker),
+	}
+
+	p, err := pool.NewAdjustablePool(func(v interface{}) (pool.Worker, error) {
+		l := v.(*lane)
+		return func(ctx context.Context) {
+			e.runLane(ctx, l)
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.pool = p
+
+	if err := e.startLanes(n, nil); err != nil {
+		p.Stop()
+		return nil, err
+	}
+	return e, nil
+}
+
+// startLanes replaces the lanes with n new ones, which start after the
+// lanes of after are done.
+func (e *Executor) startLanes(n int, after []*lane) error {
+	e.lanes = make([]*lane, n)
+	for i := range e.lanes {
+		l := &lane{
+			ch:    make(chan *Job, e.laneSize),
+			done:  make(chan struct{}),
+			after: after,
+		}
+		e.lanes[i] = l
+		if err := e.pool.Add(1, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Executor) runLane(ctx context.Context, l *lane) {
+	defer close(l.done)
+
+	for _, prev := range l.after {
+		select {
+		case <-ctx.Done():
+			return
+		case <-prev.done:
+		}
+	}
+	l.after = nil
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job, ok := <-l.ch:
+			if !ok {
+				return
+			}
+			if err := runJob(ctx, job); err != nil {
+				e.abandon(job.Partition, job.Offset)
+				if e.errHandle != nil {
+					e.errHandle(*job, err)
+				}
+				continue
+			}
+			e.complete(job.Partition, job.Offset)
+		}
+	}
+}
+
+func runJob(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job of key %q panicked: %v", job.Key, r)
+		}
+	}()
+	return job.Fn(ctx)
+}
+
+// Submit puts the job into the lane of its key, blocks while the lane is full
+// or the executor is resizing until ctx is done.
+func (e *Executor) Submit(ctx context.Context, job Job) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if job.Fn == nil {
+		return errors.New("must provide function for job")
+	}
+
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	if e.closed {
+		return ErrClosed
+	}
+
+	if err := e.track(job.Partition, job.Offset); err != nil {
+		return err
+	}
+
+	l := e.lanes[laneOf(job.Key, len(e.lanes))]
+	select {
+	case l.ch <- &job:
+		return nil
+	case <-ctx.Done():
+		// the job will never run, its offset is not committed until it is submitted again
+		e.abandon(job.Partition, job.Offset)
+		return ctx.Err()
+	}
+}
+
+func laneOf(key string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// Lanes returns the number of lanes.
+func (e *Executor) Lanes() int {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	return len(e.lanes)
+}
+
+// drain closes the lanes and waits until they processed every job,
+// it must be called with the lock held for writing.
+func (e *Executor) drain(ctx context.Context) error {
+	for _, l := range e.lanes {
+		close(l.ch)
+	}
+	for _, l := range e.lanes {
+		select {
+		case <-l.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Resize drains every lane before the keys are remapped onto n lanes, so
+// that the jobs of a key never run concurrently across the resize.
+// Submissions are blocked during the resize. If ctx is done before the
+// lanes are drained, the number of lanes is kept and the new lanes start
+// after the old ones have drained.
+func (e *Executor) Resize(ctx context.Context, n int) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if n <= 0 {
+		return errors.New("number of lanes must be positive")
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.closed {
+		return ErrClosed
+	}
+	if n == len(e.lanes) {
+		return nil
+	}
+
+	prev := e.lanes
+	if err := e.drain(ctx); err != nil {
+		// the closed lanes can not be reused, replace them with the same number
+		if serr := e.startLanes(len(prev), prev); serr != nil {
+			return serr
+		}
+		return err
+	}
+	return e.startLanes(n, nil)
+}
+
+// Close processes the submitted jobs until ctx is done and stops the
+// executor, the running jobs are canceled once ctx is done.
+func (e *Executor) Close(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.closed {
+		return ErrClosed
+	}
+	e.closed = true
+
+	err := e.drain(ctx)
+	if serr := e.pool.Shutdown(ctx); err == nil {
+		err = serr
+	}
+	return err
+}
@@ -0,0 +1,237 @@
+package keyed
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorOrderPerKey(t *testing.T) {
+	e, err := New(4, 10, nil)
+	assert.Nil(t, err)
+
+	var lock sync.Mutex
+	seen := make(map[string][]int)
+
+	var offset int64
+	submit := func(n int) {
+		for i := 0; i < n; i++ {
+			key := fmt.Sprintf("key-%d", i%7)
+			seq := int(offset)
+			err := e.Submit(context.Background(), Job{
+				Key:    key,
+				Offset: offset,
+				Fn: func(ctx context.Context) error {
+					time.Sleep(time.Duration(seq%3) * 100 * time.Microsecond)
+					lock.Lock()
+					seen[key] = append(seen[key], seq)
+					lock.Unlock()
+					return nil
+				},
+			})
+			assert.Nil(t, err)
+			offset++
+		}
+	}
+
+	submit(100)
+	assert.Nil(t, e.Resize(context.Background(), 8))
+	assert.Equal(t, 8, e.Lanes())
+	submit(100)
+	assert.Nil(t, e.Resize(context.Background(), 2))
+	submit(100)
+
+	assert.Nil(t, e.Close(context.Background()))
+
+	assert.Len(t, seen, 7)
+	for key, seqs := range seen {
+		for i := 1; i < len(seqs); i++ {
+			if seqs[i] <= seqs[i-1] {
+				t.Fatalf("out of order for %s: %v", key, seqs)
+			}
+		}
+	}
+
+	committed, ok := e.Committed(0)
+	assert.True(t, ok)
+	assert.Equal(t, int64(299), committed)
+	assert.Equal(t, 0, e.Pending(0))
+	assert.Equal(t, ErrClosed, e.Submit(context.Background(), Job{Fn: func(ctx context.Context) error { return nil }}))
+}
+
+func TestExecutorCommittedOffsets(t *testing.T) {
+	var (
+		lock   sync.Mutex
+		failed []int64
+	)
+	failures := func() []int64 {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]int64(nil), failed...)
+	}
+	e, err := New(2, 10, func(job Job, err error) {
+		lock.Lock()
+		failed = append(failed, job.Offset)
+		lock.Unlock()
+	})
+	assert.Nil(t, err)
+	defer e.Close(context.Background())
+
+	release := make(chan struct{})
+	blocked := func(ctx context.Context) error {
+		<-release
+		return nil
+	}
+	noop := func(ctx context.Context) error { return nil }
+
+	// key "a" and "b" are on different lanes
+	assert.NotEqual(t, laneOf("a", 2), laneOf("b", 2))
+
+	assert.Nil(t, e.Submit(context.Background(), Job{Key: "a", Partition: 1, Offset: 10, Fn: blocked}))
+	assert.Nil(t, e.Submit(context.Background(), Job{Key: "b", Partition: 1, Offset: 11, Fn: noop}))
+	assert.Nil(t, e.Submit(context.Background(), Job{Key: "b", Partition: 1, Offset: 12, Fn: func(ctx context.Context) error {
+		panic("boom")
+	}}))
+
+	assert.Eventually(t, func() bool {
+		return len(failures()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 2, e.Pending(1))
+
+	_, ok := e.Committed(1)
+	assert.False(t, ok)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		offset, ok := e.Committed(1)
+		return ok && offset == 11
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []int64{12}, failures())
+
+	// the failed offset is not committed until its job is submitted again
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, map[int32]int64{1: 11}, e.Offsets())
+	assert.Equal(t, 1, e.Pending(1))
+
+	assert.Nil(t, e.Submit(context.Background(), Job{Key: "b", Partition: 1, Offset: 12, Fn: noop}))
+	assert.Eventually(t, func() bool {
+		offset, ok := e.Committed(1)
+		return ok && offset == 12
+	}, time.Second, time.Millisecond)
+}
+
+func TestExecutorDuplicateOffsets(t *testing.T) {
+	e, err := New(4, 10, nil)
+	assert.Nil(t, err)
+
+	for i := 0; i < 1000; i++ {
+		assert.Nil(t, e.Submit(context.Background(), Job{
+			Key: fmt.Sprint(i),
+			Fn:  func(ctx context.Context) error { return nil },
+		}))
+	}
+	assert.Nil(t, e.Close(context.Background()))
+
+	committed, ok := e.Committed(0)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), committed)
+	assert.Equal(t, 0, e.Pending(0))
+	assert.Len(t, e.offsets[0].queue, 0)
+}
+
+func TestExecutorAbandonedOffset(t *testing.T) {
+	e, err := New(1, 1, nil)
+	assert.Nil(t, err)
+	defer e.Close(context.Background())
+
+	release := make(chan struct{})
+	noop := func(ctx context.Context) error { return nil }
+	assert.Nil(t, e.Submit(context.Background(), Job{Offset: 0, Fn: func(ctx context.Context) error {
+		<-release
+		return nil
+	}}))
+	assert.Nil(t, e.Submit(context.Background(), Job{Offset: 1, Fn: noop}))
+
+	// the lane is full, offset 2 is never queued
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, e.Submit(ctx, Job{Offset: 2, Fn: noop}))
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		offset, ok := e.Committed(0)
+		return ok && offset == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	offset, _ := e.Committed(0)
+	assert.Equal(t, int64(1), offset)
+	assert.Equal(t, 1, e.Pending(0))
+
+	// offsets lower than the pending ones are rejected
+	assert.Equal(t, ErrOffsetOrder, e.Submit(context.Background(), Job{Offset: 1, Fn: noop}))
+
+	assert.Nil(t, e.Submit(context.Background(), Job{Offset: 2, Fn: noop}))
+	assert.Eventually(t, func() bool {
+		offset, ok := e.Committed(0)
+		return ok && offset == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestExecutorResizeTimeout(t *testing.T) {
+	e, err := New(2, 10, nil)
+	assert.Nil(t, err)
+
+	var lock sync.Mutex
+	var seen []int
+	release := make(chan struct{})
+	record := func(i int) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			if i == 0 {
+				<-release
+			}
+			lock.Lock()
+			seen = append(seen, i)
+			lock.Unlock()
+			return nil
+		}
+	}
+
+	assert.Nil(t, e.Submit(context.Background(), Job{Key: "a", Offset: 0, Fn: record(0)}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, e.Resize(ctx, 4))
+	assert.Equal(t, 2, e.Lanes())
+
+	// the executor is still usable and keeps the order of the key
+	assert.Nil(t, e.Submit(context.Background(), Job{Key: "a", Offset: 1, Fn: record(1)}))
+	close(release)
+	assert.Nil(t, e.Close(context.Background()))
+	assert.Equal(t, []int{0, 1}, seen)
+
+	committed, _ := e.Committed(0)
+	assert.Equal(t, int64(1), committed)
+}
+
+func TestExecutorCloseTimeout(t *testing.T) {
+	e, err := New(1, 1, nil)
+	assert.Nil(t, err)
+
+	// the job ignores its ctx for a while
+	release := make(chan struct{})
+	assert.Nil(t, e.Submit(context.Background(), Job{Fn: func(ctx context.Context) error {
+		<-release
+		return nil
+	}}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, e.Close(ctx))
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	close(release)
+}
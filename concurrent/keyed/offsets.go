@@ -0,0 +1,136 @@
+package keyed
+
+import "errors"
+
+var ErrOffsetOrder = errors.New("offset is lower than the previous one of the partition")
+
+// offsetEntry is an offset of a partition, it is done once every job
+// submitted with it has succeeded and every abandoned job has been submitted again.
+type offsetEntry struct {
+	offset    int64
+	refs      int // jobs of the offset which have not completed
+	abandoned int // jobs of the offset which were never queued or failed
+	done      bool
+}
+
+// offsetTracker keeps the submitted offsets of a partition in order and
+// advances the committed offset while the lowest ones are done.
+type offsetTracker struct {
+	queue     []*offsetEntry
+	entries   map[int64]*offsetEntry
+	last      int64 // the highest tracked offset
+	committed int64
+	ok        bool
+}
+
+// track registers a job of the offset, a pending offset may be tracked
+// again, otherwise the offset must not be lower than the previous one.
+func (e *Executor) track(partition int32, offset int64) error {
+	e.offsetLock.Lock()
+	defer e.offsetLock.Unlock()
+
+	t, ok := e.offsets[partition]
+	if !ok {
+		t = &offsetTracker{entries: make(map[int64]*offsetEntry)}
+		e.offsets[partition] = t
+	} else if entry, ok := t.entries[offset]; ok {
+		entry.refs++
+		if entry.abandoned > 0 {
+			// the job submitted again replaces an abandoned one
+			entry.abandoned--
+		}
+		return nil
+	} else if offset < t.last {
+		return ErrOffsetOrder
+	}
+
+	entry := &offsetEntry{offset: offset, refs: 1}
+	t.queue = append(t.queue, entry)
+	t.entries[offset] = entry
+	t.last = offset
+	return nil
+}
+
+// abandon abandons a job of the offset which was never queued or failed,
+// the offset stays pending so the committed offset does not move past it
+// until the job is submitted again.
+func (e *Executor) abandon(partition int32, offset int64) {
+	e.offsetLock.Lock()
+	defer e.offsetLock.Unlock()
+
+	if entry, ok := e.offsetEntry(partition, offset); ok {
+		entry.refs--
+		entry.abandoned++
+	}
+}
+
+func (e *Executor) complete(partition int32, offset int64) {
+	e.offsetLock.Lock()
+	defer e.offsetLock.Unlock()
+
+	entry, ok := e.offsetEntry(partition, offset)
+	if !ok {
+		return
+	}
+	entry.refs--
+	if entry.refs > 0 || entry.abandoned > 0 {
+		return
+	}
+
+	t := e.offsets[partition]
+	entry.done = true
+	delete(t.entries, offset)
+
+	for len(t.queue) > 0 && t.queue[0].done {
+		t.committed, t.ok = t.queue[0].offset, true
+		t.queue[0] = nil
+		t.queue = t.queue[1:]
+	}
+}
+
+func (e *Executor) offsetEntry(partition int32, offset int64) (*offsetEntry, bool) {
+	t, ok := e.offsets[partition]
+	if !ok {
+		return nil, false
+	}
+	entry, ok := t.entries[offset]
+	return entry, ok
+}
+
+// Committed returns the highest offset of the partition whose job and the
+// jobs of every lower offset have succeeded, it is safe to commit. A failed
+// job holds the committed offset back until it is submitted again.
+func (e *Executor) Committed(partition int32) (offset int64, ok bool) {
+	e.offsetLock.Lock()
+	defer e.offsetLock.Unlock()
+
+	if t, exists := e.offsets[partition]; exists {
+		return t.committed, t.ok
+	}
+	return 0, false
+}
+
+// Offsets returns the committed offsets of every partition which has one.
+func (e *Executor) Offsets() map[int32]int64 {
+	e.offsetLock.Lock()
+	defer e.offsetLock.Unlock()
+
+	offsets := make(map[int32]int64, len(e.offsets))
+	for partition, t := range e.offsets {
+		if t.ok {
+			offsets[partition] = t.committed
+		}
+	}
+	return offsets
+}
+
+// Pending returns the number of submitted jobs of the partition which have not completed.
+func (e *Executor) Pending(partition int32) int {
+	e.offsetLock.Lock()
+	defer e.offsetLock.Unlock()
+
+	if t, ok := e.offsets[partition]; ok {
+		return len(t.entries)
+	}
+	return 0
+}
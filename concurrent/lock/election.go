@@ -0,0 +1,173 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type ElectionConfig struct {
+	TTL time.Duration // TTL of the leadership lock, default 10s
+	// RenewInterval is how often the leader extends the lock, default TTL/3.
+	// The leader steps down RenewInterval before the lock expires if it
+	// could not extend the lock, so it must be shorter than TTL.
+	RenewInterval time.Duration
+	RetryInterval time.Duration // how often a follower tries to acquire the lock, default TTL/3
+	ErrHandle     func(error)
+}
+
+// Election runs a callback on exactly one of the replicas campaigning for the same key.
+type Election struct {
+	config ElectionConfig
+	mutex  *Mutex
+}
+
+func NewElection(client redis.UniversalClient, key string, config ElectionConfig) *Election {
+	if config.TTL <= 0 {
+		config.TTL = 10 * time.Second
+	}
+	if config.RenewInterval <= 0 || config.RenewInterval >= config.TTL {
+		config.RenewInterval = config.TTL / 3
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = config.TTL / 3
+	}
+
+	return &Election{
+		config: config,
+		mutex:  NewMutex(client, key, config.TTL),
+	}
+}
+
+// Run campaigns until ctx is done. Once elected fn is called with a context
+// which is canceled when the leadership is lost, and with the fencing token
+// of the leadership. After fn returned the leadership is released, Run
+// returns if ctx is done or campaigns again otherwise.
+func (e *Election) Run(ctx context.Context, fn func(ctx context.Context, fence int64)) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		// the lease starts no earlier than the acquiring call
+		start := time.Now()
+		fence, err := e.mutex.TryLock(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != ErrNotAcquired {
+				e.handleErr(err)
+			}
+			if !sleep(ctx, e.config.RetryInterval) {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		e.lead(ctx, fence, start, fn)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// lead runs fn while renewing the leadership. The leadership is given up
+// RenewInterval before the lease expires unless it has been extended, so
+// that the next leader is not elected while fn is still running.
+func (e *Election) lead(ctx context.Context, fence int64, start time.Time, fn func(ctx context.Context, fence int64)) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(leaderCtx, fence)
+	}()
+
+	ticker := time.NewTicker(e.config.RenewInterval)
+	defer ticker.Stop()
+
+	deadline := start.Add(e.config.TTL - e.config.RenewInterval)
+	lease := time.NewTimer(time.Until(deadline))
+	defer lease.Stop()
+
+	stepDown := func() {
+		cancel()
+		<-done
+		e.release()
+	}
+
+	for {
+		select {
+		case <-done:
+			e.release()
+			return
+		case <-leaderCtx.Done():
+			stepDown()
+			return
+		case <-lease.C:
+			stepDown()
+			return
+		case <-ticker.C:
+			start := time.Now()
+			err := e.extend(leaderCtx, deadline)
+			if err == nil {
+				deadline = start.Add(e.config.TTL - e.config.RenewInterval)
+				if !lease.Stop() {
+					<-lease.C
+				}
+				lease.Reset(time.Until(deadline))
+				continue
+			}
+			e.handleErr(err)
+
+			// the lock may still be held after a transient error until the deadline
+			if err == ErrNotHeld {
+				stepDown()
+				return
+			}
+		}
+	}
+}
+
+// extend extends the lock, the call is bounded by the deadline of the lease.
+func (e *Election) extend(ctx context.Context, deadline time.Time) error {
+	timeout := time.Until(deadline)
+	if timeout > e.config.RenewInterval {
+		timeout = e.config.RenewInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return e.mutex.Extend(ctx)
+}
+
+func (e *Election) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.config.RenewInterval)
+	defer cancel()
+
+	if err := e.mutex.Unlock(ctx); err != nil && err != ErrNotHeld {
+		e.handleErr(err)
+	}
+}
+
+func (e *Election) handleErr(err error) {
+	if e.config.ErrHandle != nil {
+		e.config.ErrHandle(err)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,174 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func newClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mr, redis.NewClient(&redis.Options{Addr: mr.Addr()})
+}
+
+func TestMutex(t *testing.T) {
+	mr, client := newClient(t)
+	defer mr.Close()
+	ctx := context.Background()
+
+	m1 := NewMutex(client, "scan", time.Second)
+	m2 := NewMutex(client, "scan", time.Second)
+
+	fence, err := m1.TryLock(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), fence)
+	assert.Equal(t, int64(1), m1.Fence())
+
+	_, err = m2.TryLock(ctx)
+	assert.Equal(t, ErrNotAcquired, err)
+	assert.Equal(t, ErrNotHeld, m2.Unlock(ctx))
+
+	assert.Nil(t, m1.Extend(ctx))
+
+	// the lock of m1 expires and m2 takes it with a greater fence
+	mr.FastForward(2 * time.Second)
+	fence, err = m2.Lock(ctx, time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), fence)
+
+	// m1 can not release the lock of m2
+	assert.Equal(t, ErrNotHeld, m1.Extend(ctx))
+	assert.Equal(t, ErrNotHeld, m1.Unlock(ctx))
+	assert.True(t, mr.Exists("scan"))
+
+	assert.Nil(t, m2.Unlock(ctx))
+	assert.False(t, mr.Exists("scan"))
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = m1.Lock(timeout, time.Millisecond)
+	assert.Nil(t, err)
+	_, err = m2.Lock(timeout, time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestElection(t *testing.T) {
+	mr, client := newClient(t)
+	defer mr.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var leaders, runs int32
+	var lock sync.Mutex
+	var fences []int64
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e := NewElection(client, "leader", ElectionConfig{TTL: 300 * time.Millisecond, RetryInterval: 5 * time.Millisecond})
+			_ = e.Run(ctx, func(ctx context.Context, fence int64) {
+				n := atomic.AddInt32(&leaders, 1)
+				assert.Equal(t, int32(1), n)
+				atomic.AddInt32(&runs, 1)
+
+				lock.Lock()
+				fences = append(fences, fence)
+				lock.Unlock()
+
+				// give up the leadership after a while
+				select {
+				case <-ctx.Done():
+				case <-time.After(20 * time.Millisecond):
+				}
+				atomic.AddInt32(&leaders, -1)
+			})
+		}()
+	}
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&runs) >= 3
+	}, 2*time.Second, time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	lock.Lock()
+	defer lock.Unlock()
+	for i := 1; i < len(fences); i++ {
+		assert.True(t, fences[i] > fences[i-1])
+	}
+}
+
+func TestElectionLost(t *testing.T) {
+	mr, client := newClient(t)
+	defer mr.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	elected := make(chan struct{})
+	lost := make(chan struct{})
+	e := NewElection(client, "leader", ElectionConfig{TTL: 30 * time.Millisecond})
+	go e.Run(ctx, func(ctx context.Context, fence int64) {
+		if fence == 1 {
+			close(elected)
+			<-ctx.Done()
+			close(lost)
+		}
+		<-ctx.Done()
+	})
+
+	<-elected
+	// another replica takes over the lock
+	mr.Set("leader", "other")
+
+	select {
+	case <-lost:
+	case <-time.After(time.Second):
+		t.Fatal("leadership is not lost")
+	}
+}
+
+func TestElectionStepDown(t *testing.T) {
+	mr, client := newClient(t)
+	defer mr.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const ttl = 300 * time.Millisecond
+	elected := make(chan time.Time, 1)
+	lost := make(chan time.Time, 1)
+	e := NewElection(client, "leader", ElectionConfig{TTL: ttl, RenewInterval: 50 * time.Millisecond})
+	go e.Run(ctx, func(ctx context.Context, fence int64) {
+		if fence != 1 {
+			<-ctx.Done()
+			return
+		}
+		elected <- time.Now()
+		<-ctx.Done()
+		lost <- time.Now()
+	})
+
+	start := <-elected
+	// the lock can not be extended but is still held in redis
+	mr.SetError("unavailable")
+
+	select {
+	case end := <-lost:
+		// stepped down before the lock expired
+		assert.True(t, end.Sub(start) < ttl, "stepped down after %v", end.Sub(start))
+	case <-time.After(time.Second):
+		t.Fatal("leader did not step down")
+	}
+}
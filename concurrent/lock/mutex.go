@@ -0,0 +1,186 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	ErrNotAcquired = errors.New("lock is held by others")
+	ErrNotHeld     = errors.New("lock is not held")
+)
+
+var (
+	acquireScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0
+`)
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+	extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+)
+
+// Mutex is a redis lock acquired by SET NX PX with a random value, so that
+// only the holder can release or extend it. Every acquisition increments
+// a fencing token stored at key+":fence" which the holder should pass to
+// the protected resource to reject stale holders.
+// Use a hash tag in key, e.g. "{scan}:lock", with redis cluster.
+type Mutex struct {
+	client   redis.UniversalClient
+	key      string
+	fenceKey string
+	ttl      time.Duration
+
+	lock  sync.Mutex
+	value string
+	fence int64
+}
+
+func NewMutex(client redis.UniversalClient, key string, ttl time.Duration) *Mutex {
+	if ttl <= 0 {
+		ttl = 10 * time.Second
+	}
+	return &Mutex{
+		client:   client,
+		key:      key,
+		fenceKey: key + ":fence",
+		ttl:      ttl,
+	}
+}
+
+func (m *Mutex) Key() string {
+	return m.key
+}
+
+// TryLock acquires the lock once, returns ErrNotAcquired if it is held by others.
+func (m *Mutex) TryLock(ctx context.Context) (fence int64, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	value, err := randomValue()
+	if err != nil {
+		return 0, err
+	}
+
+	fence, err = acquireScript.Run(ctx, m.client, []string{m.key, m.fenceKey},
+		value, m.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if fence == 0 {
+		return 0, ErrNotAcquired
+	}
+
+	m.lock.Lock()
+	m.value, m.fence = value, fence
+	m.lock.Unlock()
+	return fence, nil
+}
+
+// Lock acquires the lock, retrying every interval until ctx is done.
+func (m *Mutex) Lock(ctx context.Context, interval time.Duration) (fence int64, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fence, err := m.TryLock(ctx)
+		if err != ErrNotAcquired {
+			return fence, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unlock releases the lock if it is still held, returns ErrNotHeld otherwise.
+func (m *Mutex) Unlock(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	m.lock.Lock()
+	value := m.value
+	m.value, m.fence = "", 0
+	m.lock.Unlock()
+
+	if value == "" {
+		return ErrNotHeld
+	}
+
+	n, err := releaseScript.Run(ctx, m.client, []string{m.key}, value).Int64()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// Extend resets the TTL of the lock if it is still held, returns ErrNotHeld otherwise.
+func (m *Mutex) Extend(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	m.lock.Lock()
+	value := m.value
+	m.lock.Unlock()
+
+	if value == "" {
+		return ErrNotHeld
+	}
+
+	n, err := extendScript.Run(ctx, m.client, []string{m.key}, value, m.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// Fence returns the fencing token of the current acquisition, 0 if not held.
+func (m *Mutex) Fence() int64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.fence
+}
+
+func randomValue() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,190 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shima-park/tools/concurrent/pool"
+)
+
+type MapFunc func(ctx context.Context, v interface{}) (interface{}, error)
+
+type FilterFunc func(ctx context.Context, v interface{}) (bool, error)
+
+type FlatMapFunc func(ctx context.Context, v interface{}) ([]interface{}, error)
+
+type SinkFunc func(ctx context.Context, v interface{}) error
+
+type StageConfig struct {
+	Name    string
+	Workers int  // number of workers of the stage, default 1
+	Buffer  int  // size of the channel to the next stage, default Workers
+	Ordered bool // Ordered keeps the output in the order of the input
+}
+
+// Pipeline connects stages running on their own AdjustablePool by bounded
+// channels, the first error of any stage cancels the whole pipeline.
+type Pipeline struct {
+	source <-chan interface{}
+	stages []*Stage
+	err    error
+
+	lock    sync.Mutex
+	started bool
+}
+
+func New(source <-chan interface{}) *Pipeline {
+	return &Pipeline{source: source}
+}
+
+// Map emits fn(v) for every item.
+func (p *Pipeline) Map(config StageConfig, fn MapFunc) *Pipeline {
+	if fn == nil {
+		return p.fail(config, errors.New("must provide function for map stage"))
+	}
+	return p.addStage(config, func(ctx context.Context, v interface{}) ([]interface{}, error) {
+		out, err := fn(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{out}, nil
+	})
+}
+
+// Filter emits the items for which fn returns true.
+func (p *Pipeline) Filter(config StageConfig, fn FilterFunc) *Pipeline {
+	if fn == nil {
+		return p.fail(config, errors.New("must provide function for filter stage"))
+	}
+	return p.addStage(config, func(ctx context.Context, v interface{}) ([]interface{}, error) {
+		ok, err := fn(ctx, v)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []interface{}{v}, nil
+	})
+}
+
+// FlatMap emits every item returned by fn.
+func (p *Pipeline) FlatMap(config StageConfig, fn FlatMapFunc) *Pipeline {
+	if fn == nil {
+		return p.fail(config, errors.New("must provide function for flat map stage"))
+	}
+	return p.addStage(config, fn)
+}
+
+// Batch emits []interface{} of up to size items, a partial batch is emitted
+// after interval since its first item if interval is positive.
+func (p *Pipeline) Batch(config StageConfig, size int, interval time.Duration) *Pipeline {
+	if size <= 0 {
+		return p.fail(config, errors.New("batch size must be positive"))
+	}
+	s := p.addStage(config, func(ctx context.Context, v interface{}) ([]interface{}, error) {
+		return []interface{}{v}, nil
+	})
+	stage := s.stages[len(s.stages)-1]
+	stage.batchSize = size
+	stage.batchInterval = interval
+	return s
+}
+
+// Sink consumes every item, it is usually the last stage.
+func (p *Pipeline) Sink(config StageConfig, fn SinkFunc) *Pipeline {
+	if fn == nil {
+		return p.fail(config, errors.New("must provide function for sink stage"))
+	}
+	return p.addStage(config, func(ctx context.Context, v interface{}) ([]interface{}, error) {
+		return nil, fn(ctx, v)
+	})
+}
+
+func (p *Pipeline) fail(config StageConfig, err error) *Pipeline {
+	if p.err == nil {
+		p.err = fmt.Errorf("stage %q: %w", config.Name, err)
+	}
+	return p
+}
+
+func (p *Pipeline) addStage(config StageConfig, fn FlatMapFunc) *Pipeline {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.Buffer <= 0 {
+		config.Buffer = config.Workers
+	}
+	if config.Name == "" {
+		config.Name = fmt.Sprintf("stage-%d", len(p.stages))
+	}
+
+	tp, err := pool.NewTaskPool(config.Buffer)
+	if err != nil {
+		return p.fail(config, err)
+	}
+
+	p.stages = append(p.stages, &Stage{
+		config: config,
+		fn:     fn,
+		pool:   tp,
+	})
+	return p
+}
+
+// Stage returns the stage by name, nil if it does not exist.
+func (p *Pipeline) Stage(name string) *Stage {
+	for _, s := range p.stages {
+		if s.config.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Run runs the pipeline until the source is closed and every item has been
+// processed, or until the first error. The output of the last stage is
+// discarded unless it is a Sink. A pipeline can only be run once.
+func (p *Pipeline) Run(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if p.err != nil {
+		return p.err
+	}
+
+	p.lock.Lock()
+	if p.started {
+		p.lock.Unlock()
+		return errors.New("pipeline has already been run")
+	}
+	p.started = true
+	p.lock.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var runErr error
+	fail := func(err error) {
+		once.Do(func() {
+			if ctx.Err() != nil {
+				// canceled by the parent context
+				runErr = ctx.Err()
+				return
+			}
+			runErr = err
+			cancel()
+		})
+	}
+
+	in := p.source
+	for _, s := range p.stages {
+		in = s.start(ctx, in, fail)
+	}
+	for range in {
+	}
+
+	fail(nil)
+	return runErr
+}
@@ -0,0 +1,140 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func source(n int) <-chan interface{} {
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		for i := 0; i < n; i++ {
+			ch <- i
+		}
+	}()
+	return ch
+}
+
+func TestPipelineOrdered(t *testing.T) {
+	var got []interface{}
+	var p *Pipeline
+	var maxSize int32
+	p = New(source(100)).
+		Map(StageConfig{Name: "square", Workers: 8, Ordered: true}, func(ctx context.Context, v interface{}) (interface{}, error) {
+			if n := int32(p.Stage("square").Pool().Size()); n > atomic.LoadInt32(&maxSize) {
+				atomic.StoreInt32(&maxSize, n)
+			}
+			i := v.(int)
+			time.Sleep(time.Duration(i%3) * time.Millisecond)
+			return i * i, nil
+		}).
+		Filter(StageConfig{Name: "even", Workers: 4, Ordered: true}, func(ctx context.Context, v interface{}) (bool, error) {
+			return v.(int)%2 == 0, nil
+		}).
+		FlatMap(StageConfig{Name: "twice", Workers: 4, Ordered: true}, func(ctx context.Context, v interface{}) ([]interface{}, error) {
+			return []interface{}{v, v}, nil
+		}).
+		Batch(StageConfig{Name: "batch", Ordered: true}, 10, time.Millisecond).
+		Sink(StageConfig{Name: "sink"}, func(ctx context.Context, v interface{}) error {
+			got = append(got, v.([]interface{})...)
+			return nil
+		})
+
+	assert.Nil(t, p.Stage("square").Resize(2))
+	assert.Nil(t, p.Run(context.Background()))
+	// the size set before Run is kept
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxSize))
+
+	var want []interface{}
+	for i := 0; i < 100; i += 2 {
+		want = append(want, i*i, i*i)
+	}
+	assert.Equal(t, want, got)
+
+	assert.NotNil(t, p.Run(context.Background()))
+}
+
+func TestPipelineUnordered(t *testing.T) {
+	var lock sync.Mutex
+	sum := 0
+	err := New(source(1000)).
+		Map(StageConfig{Workers: 16}, func(ctx context.Context, v interface{}) (interface{}, error) {
+			return v.(int) + 1, nil
+		}).
+		Sink(StageConfig{Workers: 4}, func(ctx context.Context, v interface{}) error {
+			lock.Lock()
+			sum += v.(int)
+			lock.Unlock()
+			return nil
+		}).
+		Run(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1000*1001/2, sum)
+}
+
+func TestPipelineResizeWhileRunning(t *testing.T) {
+	var p *Pipeline
+	var once sync.Once
+	var count int32
+	p = New(source(200)).
+		Map(StageConfig{Name: "slow", Workers: 8}, func(ctx context.Context, v interface{}) (interface{}, error) {
+			if v.(int) == 50 {
+				once.Do(func() {
+					go func() {
+						assert.Nil(t, p.Stage("slow").Resize(1))
+					}()
+				})
+			}
+			time.Sleep(time.Millisecond)
+			return v, nil
+		}).
+		Sink(StageConfig{}, func(ctx context.Context, v interface{}) error {
+			atomic.AddInt32(&count, 1)
+			return nil
+		})
+
+	assert.Nil(t, p.Run(context.Background()))
+	assert.Equal(t, int32(200), atomic.LoadInt32(&count))
+}
+
+func TestPipelineError(t *testing.T) {
+	stageErr := errors.New("bad item")
+	ch := make(chan interface{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case ch <- i:
+			case <-time.After(time.Second):
+				return
+			}
+		}
+	}()
+
+	err := New(ch).
+		Map(StageConfig{Name: "check", Workers: 4}, func(ctx context.Context, v interface{}) (interface{}, error) {
+			if v.(int) == 50 {
+				return nil, stageErr
+			}
+			return v, nil
+		}).
+		Sink(StageConfig{}, func(ctx context.Context, v interface{}) error { return nil }).
+		Run(context.Background())
+	assert.True(t, errors.Is(err, stageErr))
+	assert.Contains(t, err.Error(), `stage "check"`)
+}
+
+func TestPipelinePanic(t *testing.T) {
+	err := New(source(10)).
+		Map(StageConfig{Ordered: true}, func(ctx context.Context, v interface{}) (interface{}, error) {
+			panic("boom")
+		}).
+		Run(context.Background())
+	assert.NotNil(t, err)
+}
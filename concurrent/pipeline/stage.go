@@ -0,0 +1,214 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shima-park/tools/concurrent/pool"
+)
+
+// Stage runs its function on an AdjustablePool.
+type Stage struct {
+	config StageConfig
+	fn     FlatMapFunc
+	pool   *pool.AdjustablePool
+
+	lock sync.Mutex // guards config.Workers and the size of pool
+
+	batchSize     int
+	batchInterval time.Duration
+}
+
+func (s *Stage) Name() string {
+	return s.config.Name
+}
+
+// Resize sets the number of workers of the stage, it can be called while
+// running. The removed workers finish their current items before they exit.
+func (s *Stage) Resize(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("stage %q: workers must be positive", s.config.Name)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.config.Workers = n
+	return s.pool.SetSize(n)
+}
+
+// Pool returns the pool of the stage, e.g. for an Autoscaler.
+func (s *Stage) Pool() *pool.AdjustablePool {
+	return s.pool
+}
+
+type result struct {
+	future *pool.Future
+	values []interface{}
+}
+
+func (s *Stage) start(ctx context.Context, in <-chan interface{}, fail func(error)) <-chan interface{} {
+	out := make(chan interface{}, s.config.Buffer)
+
+	s.pool.SetPanicHandler(func(_ interface{}, recovered interface{}, stack []byte) {
+		fail(fmt.Errorf("stage %q: %w", s.config.Name, &pool.PanicError{Recovered: recovered, Stack: stack}))
+	})
+	s.lock.Lock()
+	err := s.pool.SetSize(s.config.Workers)
+	s.lock.Unlock()
+	if err != nil {
+		fail(fmt.Errorf("stage %q: %w", s.config.Name, err))
+	}
+
+	var order chan *result
+	if s.config.Ordered {
+		order = make(chan *result, s.config.Buffer)
+		go s.collect(ctx, order, out, fail)
+	}
+
+	// the values are emitted until the pipeline is canceled, rather than
+	// the task whose worker may have been removed by Resize
+	emit := func(values []interface{}) error {
+		for _, v := range values {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+
+	// the items submitted but never processed are reported once the pool
+	// is shut down, so an item is never lost silently
+	var submitted, processed int64
+
+	submit := func(v interface{}) bool {
+		r := &result{}
+		f, err := s.pool.Submit(ctx, func(ctx context.Context) error {
+			defer atomic.AddInt64(&processed, 1)
+
+			values, err := s.fn(ctx, v)
+			if err != nil {
+				fail(fmt.Errorf("stage %q: %w", s.config.Name, err))
+				return err
+			}
+			if order != nil {
+				r.values = values
+				return nil
+			}
+			return emit(values)
+		})
+		if err != nil {
+			return false
+		}
+		atomic.AddInt64(&submitted, 1)
+		if order != nil {
+			r.future = f
+			select {
+			case order <- r:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		return true
+	}
+
+	go func() {
+		s.dispatch(ctx, in, submit)
+
+		_ = s.pool.Shutdown(context.Background())
+		if n := atomic.LoadInt64(&submitted) - atomic.LoadInt64(&processed); n > 0 && ctx.Err() == nil {
+			fail(fmt.Errorf("stage %q: %d items were not processed", s.config.Name, n))
+		}
+		if order != nil {
+			close(order)
+		} else {
+			close(out)
+		}
+	}()
+	return out
+}
+
+// dispatch submits the items of in, or the batches of them for a batch stage.
+func (s *Stage) dispatch(ctx context.Context, in <-chan interface{}, submit func(interface{}) bool) {
+	if s.batchSize <= 0 {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok || !submit(v) {
+					return
+				}
+			}
+		}
+	}
+
+	var batch []interface{}
+	var timeout <-chan time.Time
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	flush := func() bool {
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+		if len(batch) == 0 {
+			return true
+		}
+		b := batch
+		batch = nil
+		return submit(b)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout:
+			if !flush() {
+				return
+			}
+		case v, ok := <-in:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, v)
+			if len(batch) == 1 && s.batchInterval > 0 {
+				timer = time.NewTimer(s.batchInterval)
+				timeout = timer.C
+			}
+			if len(batch) >= s.batchSize && !flush() {
+				return
+			}
+		}
+	}
+}
+
+// collect emits the results of an ordered stage in the order of submission.
+func (s *Stage) collect(ctx context.Context, order <-chan *result, out chan<- interface{}, fail func(error)) {
+	defer close(out)
+
+	for r := range order {
+		if err := r.future.Wait(ctx); err != nil {
+			fail(fmt.Errorf("stage %q: %w", s.config.Name, err))
+			continue
+		}
+		for _, v := range r.values {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+			}
+		}
+	}
+}
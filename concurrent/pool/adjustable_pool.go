@@ -3,8 +3,10 @@ package pool
 import (
 	"context"
 	"errors"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type AdjustablePool struct {
@@ -15,10 +17,38 @@ type AdjustablePool struct {
 	lock    sync.Mutex
 	workers []*ContextWithWorker
 	done    chan struct{}
+	abort   chan struct{} // closed to cancel the running tasks
+
+	nextID   uint64
+	alive    map[*ContextWithWorker]struct{}
+	draining chan struct{}
+
+	tasks      chan *task
+	queue      taskQueue
+	submitLock sync.RWMutex
+
+	restart      RestartPolicy
+	panicHandler PanicHandler
+	metrics      atomic.Value
+
+	limiter     *Limiter
+	limitLock   sync.Mutex
+	keyLimiters map[string]*Limiter
 }
 
 type Task struct {
-	Num int // Num is positive number or negative number
+	Num   int         // Num is positive number or negative number
+	Exact bool        // Exact means that Num is the desired size of the pool instead of a delta
+	Arg   interface{} // Arg is passed to the WorkerFactory when workers are added
+}
+
+// TaskResult reports the state of the pool after a Task has been applied.
+type TaskResult struct {
+	Task    Task
+	Size    int // Size is the number of workers the pool converges to
+	Running int
+	Exiting int
+	Err     error
 }
 
 type WorkerFactory func(interface{}) (Worker, error)
@@ -26,9 +56,17 @@ type WorkerFactory func(interface{}) (Worker, error)
 type Worker func(ctx context.Context)
 
 type ContextWithWorker struct {
+	id        uint64
 	ctx       context.Context
 	cancel    context.CancelFunc
 	worker    Worker
+	factory   WorkerFactory
+	arg       interface{}
+	restart   RestartPolicy
+	restarts  int32
+	state     int32
+	startedAt atomic.Value // time.Time the current run started at
+	onPanic   PanicHandler
 	runBefore func()
 	runAfter  func()
 }
@@ -38,10 +76,54 @@ func (w *ContextWithWorker) Run() {
 
 	w.runBefore()
 
+	for {
+		w.setState(WorkerRestarting, WorkerRunning)
+		w.setState(WorkerStarting, WorkerRunning)
+
+		panicked := w.runOnce()
+
+		restarts := int(atomic.LoadInt32(&w.restarts))
+		if w.ctx.Err() != nil || isClosed(Draining(w.ctx)) ||
+			!w.restart.shouldRestart(panicked, restarts) {
+			return
+		}
+
+		w.setState(WorkerRunning, WorkerRestarting)
+		timer := time.NewTimer(w.restart.backoff(restarts))
+		select {
+		case <-w.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// a fresh worker is created so no state of the exited one is reused
+		worker, err := w.factory(w.arg)
+		if err != nil {
+			return
+		}
+		w.worker = worker
+		w.startedAt.Store(time.Now())
+		atomic.AddInt32(&w.restarts, 1)
+	}
+}
+
+func (w *ContextWithWorker) runOnce() (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			if w.onPanic != nil {
+				w.onPanic(w.arg, r, debug.Stack())
+			}
+		}
+	}()
+
 	w.worker(w.ctx)
+	return false
 }
 
 func (w *ContextWithWorker) Stop() {
+	atomic.StoreInt32(&w.state, int32(WorkerExiting))
 	w.cancel()
 }
 
@@ -50,33 +132,74 @@ func NewAdjustablePool(f WorkerFactory) (*AdjustablePool, error) {
 		return nil, errors.New("must provide function for pool")
 	}
 
-	p := &AdjustablePool{
-		factory: f,
-		wg:      &sync.WaitGroup{},
-		done:    make(chan struct{}),
-	}
+	p := newAdjustablePool()
+	p.factory = f
 
 	return p, nil
 }
 
-func (p *AdjustablePool) newContextWithWorker(worker Worker) *ContextWithWorker {
-	ctx, cancel := context.WithCancel(context.Background())
-	return &ContextWithWorker{
-		ctx:    ctx,
-		cancel: cancel,
-		worker: worker,
-		runBefore: func() {
-			p.wg.Add(1)
-			atomic.AddInt32(&p.running, 1)
-		},
-		runAfter: func() {
-			atomic.AddInt32(&p.running, -1)
+func newAdjustablePool() *AdjustablePool {
+	return &AdjustablePool{
+		wg:          &sync.WaitGroup{},
+		done:        make(chan struct{}),
+		abort:       make(chan struct{}),
+		alive:       make(map[*ContextWithWorker]struct{}),
+		draining:    make(chan struct{}),
+		limiter:     NewLimiter(0, 0),
+		keyLimiters: make(map[string]*Limiter),
+	}
+}
+
+func (p *AdjustablePool) newContextWithWorker(worker Worker, v interface{}) *ContextWithWorker {
+	p.nextID++
+	ctx := context.WithValue(context.Background(), drainingKey{}, p.draining)
+	ctx, cancel := context.WithCancel(ctx)
+	cw := &ContextWithWorker{
+		id:      p.nextID,
+		ctx:     ctx,
+		cancel:  cancel,
+		worker:  worker,
+		factory: p.factory,
+		arg:     v,
+		restart: p.restart,
+		onPanic: p.handlePanic,
+	}
+	cw.startedAt.Store(time.Now())
+	cw.runBefore = func() {
+		atomic.AddInt32(&p.running, 1)
+		p.getMetrics().WorkerStarted()
+	}
+	cw.runAfter = func() {
+		atomic.AddInt32(&p.running, -1)
+		p.getMetrics().WorkerStopped()
+		if !p.remove(cw) {
+			// the worker has been reduced and counted as exiting
 			atomic.AddInt32(&p.exiting, -1)
-			p.wg.Done()
-		},
+		}
+		p.wg.Done()
+	}
+	return cw
+}
+
+// remove removes the worker which exited on its own from the pool,
+// returns false if the worker has already been reduced.
+func (p *AdjustablePool) remove(cw *ContextWithWorker) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.alive, cw)
+
+	for i, w := range p.workers {
+		if w == cw {
+			p.workers = append(p.workers[:i], p.workers[i+1:]...)
+			return true
+		}
 	}
+	return false
 }
 
+// Add starts i workers created by the factory with v,
+// returns ErrPoolStopped if the pool has been stopped.
 func (p *AdjustablePool) Add(i int, v interface{}) error {
 	if i <= 0 {
 		return nil
@@ -85,21 +208,32 @@ func (p *AdjustablePool) Add(i int, v interface{}) error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
+	if p.isStopped() {
+		return ErrPoolStopped
+	}
+	return p.add(i, v)
+}
+
+func (p *AdjustablePool) add(i int, v interface{}) error {
 	for ; i > 0; i-- {
 		worker, err := p.factory(v)
 		if err != nil {
 			return err
 		}
 
-		cw := p.newContextWithWorker(worker)
+		cw := p.newContextWithWorker(worker, v)
 
 		p.workers = append(p.workers, cw)
+		p.alive[cw] = struct{}{}
 
+		p.wg.Add(1)
 		go cw.Run()
 	}
 	return nil
 }
 
+// Reduce stops the i most recently added workers,
+// it does nothing once the pool has been stopped.
 func (p *AdjustablePool) Reduce(i int) error {
 	if i <= 0 {
 		return nil
@@ -108,6 +242,14 @@ func (p *AdjustablePool) Reduce(i int) error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
+	if p.isStopped() {
+		return nil
+	}
+	p.reduce(i)
+	return nil
+}
+
+func (p *AdjustablePool) reduce(i int) {
 	for ; i > 0 && len(p.workers) > 0; i-- {
 		k := len(p.workers) - 1
 		worker := p.workers[k]
@@ -115,7 +257,82 @@ func (p *AdjustablePool) Reduce(i int) error {
 		atomic.AddInt32(&p.exiting, 1)
 		p.workers = append(p.workers[:k], p.workers[k+1:]...)
 	}
-	return nil
+}
+
+// Apply adds or reduces workers according to the task.
+func (p *AdjustablePool) Apply(t Task) TaskResult {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var err error
+	if p.isStopped() {
+		err = ErrPoolStopped
+	} else {
+		n := t.Num
+		if t.Exact {
+			if n < 0 {
+				n = 0
+			}
+			n -= len(p.workers)
+		}
+
+		if n > 0 {
+			err = p.add(n, t.Arg)
+		} else {
+			p.reduce(-n)
+		}
+	}
+
+	return TaskResult{
+		Task:    t,
+		Size:    len(p.workers),
+		Running: p.Running(),
+		Exiting: p.Exiting(),
+		Err:     err,
+	}
+}
+
+// SetSize adds or reduces workers until the pool has n workers.
+func (p *AdjustablePool) SetSize(n int) error {
+	return p.Apply(Task{Num: n, Exact: true}).Err
+}
+
+// Control applies the tasks received from ch until ch is closed or ctx is done,
+// the result of each task is sent to the returned channel which must be drained.
+func (p *AdjustablePool) Control(ctx context.Context, ch <-chan Task) <-chan TaskResult {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	results := make(chan TaskResult, 1)
+	go func() {
+		defer close(results)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case t, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				select {
+				case results <- p.Apply(t):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return results
+}
+
+// Size returns the number of workers that have been added and not reduced yet.
+func (p *AdjustablePool) Size() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.workers)
 }
 
 func (p *AdjustablePool) Running() int {
@@ -127,8 +344,31 @@ func (p *AdjustablePool) Exiting() int {
 }
 
 func (p *AdjustablePool) isStopped() bool {
+	return isClosed(p.done)
+}
+
+// markStopped closes done, returns false if the pool has already been stopped.
+func (p *AdjustablePool) markStopped() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.isStopped() {
+		return false
+	}
+	close(p.done)
+	return true
+}
+
+// abortTasks cancels the running tasks, it must be called with the lock held.
+func (p *AdjustablePool) abortTasks() {
+	if !isClosed(p.abort) {
+		close(p.abort)
+	}
+}
+
+func isClosed(ch <-chan struct{}) bool {
 	select {
-	case <-p.done:
+	case <-ch:
 		return true
 	default:
 	}
@@ -136,12 +376,16 @@ func (p *AdjustablePool) isStopped() bool {
 }
 
 func (p *AdjustablePool) Stop() {
-	if p.isStopped() {
+	if !p.markStopped() {
 		return
 	}
-	close(p.done)
 
-	_ = p.Reduce(len(p.workers))
+	p.lock.Lock()
+	p.abortTasks()
+	p.reduce(len(p.workers))
+	p.lock.Unlock()
 
 	p.wg.Wait()
+
+	p.discardTasks()
 }
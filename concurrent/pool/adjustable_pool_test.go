@@ -51,3 +51,46 @@ func TestAdjuestablePool(t *testing.T) {
 	assert.Equal(t, 0, p.Running())
 	fmt.Println("Running:", p.Running(), "Exiting:", p.Exiting())
 }
+
+func TestApplyTask(t *testing.T) {
+	p, err := NewTaskPool(1)
+	assert.Nil(t, err)
+
+	r := p.Apply(Task{Num: 3})
+	assert.Nil(t, r.Err)
+	assert.Equal(t, 3, r.Size)
+
+	r = p.Apply(Task{Num: -1})
+	assert.Nil(t, r.Err)
+	assert.Equal(t, 2, r.Size)
+
+	r = p.Apply(Task{Num: 5, Exact: true})
+	assert.Nil(t, r.Err)
+	assert.Equal(t, 5, r.Size)
+
+	assert.Nil(t, p.SetSize(0))
+	assert.Equal(t, 0, p.Size())
+
+	tasks := make(chan Task)
+	results := p.Control(context.Background(), tasks)
+	go func() {
+		defer close(tasks)
+		tasks <- Task{Num: 2}
+		tasks <- Task{Num: 4, Exact: true}
+		tasks <- Task{Num: -10}
+	}()
+
+	var sizes []int
+	for r := range results {
+		assert.Nil(t, r.Err)
+		sizes = append(sizes, r.Size)
+	}
+	assert.Equal(t, []int{2, 4, 0}, sizes)
+
+	p.Stop()
+	assert.Equal(t, ErrPoolStopped, p.SetSize(1))
+	assert.Equal(t, ErrPoolStopped, p.Add(3, nil))
+	assert.Nil(t, p.Reduce(1))
+	assert.Equal(t, 0, p.Size())
+	assert.Equal(t, 0, p.Running())
+}
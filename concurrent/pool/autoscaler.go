@@ -0,0 +1,174 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Signal reports the current load of the pool, the autoscaler compares it
+// with the configured thresholds.
+type Signal interface {
+	Load() (float64, error)
+}
+
+type SignalFunc func() (float64, error)
+
+func (f SignalFunc) Load() (float64, error) {
+	return f()
+}
+
+// PendingSignal uses the length of the pool's task queue as load.
+func PendingSignal(p *AdjustablePool) Signal {
+	return SignalFunc(func() (float64, error) {
+		return float64(p.Pending()), nil
+	})
+}
+
+// GaugeSignal uses an arbitrary gauge as load, e.g. kafka consumer lag.
+func GaugeSignal(gauge func() float64) Signal {
+	return SignalFunc(func() (float64, error) {
+		return gauge(), nil
+	})
+}
+
+type AutoscalerConfig struct {
+	MinWorkers int
+	MaxWorkers int
+
+	Signal             Signal
+	ScaleUpThreshold   float64 // scale up when load > ScaleUpThreshold
+	ScaleDownThreshold float64 // scale down when load < ScaleDownThreshold
+
+	ScaleUpStep   int           // default 1
+	ScaleDownStep int           // default 1
+	Interval      time.Duration // how often the signal is read, default 1s
+	Cooldown      time.Duration // minimum time between two scaling actions
+
+	Arg       interface{} // Arg is passed to Add when scaling up
+	ErrHandle func(error)
+}
+
+// Autoscaler drives Add/Reduce of an AdjustablePool by a Signal, the
+// workers removed by a scale-down finish their running tasks first.
+type Autoscaler struct {
+	pool   *AdjustablePool
+	config AutoscalerConfig
+
+	lock      sync.Mutex
+	lastScale time.Time
+}
+
+func NewAutoscaler(p *AdjustablePool, config AutoscalerConfig) (*Autoscaler, error) {
+	if p == nil {
+		return nil, errors.New("must provide pool for autoscaler")
+	}
+	if config.Signal == nil {
+		return nil, errors.New("must provide signal for autoscaler")
+	}
+	if config.MinWorkers < 0 || config.MaxWorkers <= 0 || config.MaxWorkers < config.MinWorkers {
+		return nil, errors.New("invalid min/max workers of autoscaler")
+	}
+	if config.ScaleDownThreshold > config.ScaleUpThreshold {
+		return nil, errors.New("scale down threshold must not be greater than scale up threshold")
+	}
+	if config.ScaleUpStep <= 0 {
+		config.ScaleUpStep = 1
+	}
+	if config.ScaleDownStep <= 0 {
+		config.ScaleDownStep = 1
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Second
+	}
+
+	return &Autoscaler{
+		pool:   p,
+		config: config,
+	}, nil
+}
+
+// Run evaluates the signal every Interval until ctx is done or the pool is stopped.
+func (a *Autoscaler) Run(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ticker := time.NewTicker(a.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.Scale(); err != nil && a.config.ErrHandle != nil {
+			a.config.ErrHandle(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.pool.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scale reads the signal once and adjusts the pool size if necessary.
+func (a *Autoscaler) Scale() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.pool.isStopped() {
+		return ErrPoolStopped
+	}
+
+	size := a.pool.Size()
+
+	// out of bounds is always corrected, regardless of the cooldown
+	if size < a.config.MinWorkers {
+		return a.scale(a.config.MinWorkers - size)
+	}
+	if size > a.config.MaxWorkers {
+		return a.scale(a.config.MaxWorkers - size)
+	}
+
+	if !a.lastScale.IsZero() && time.Since(a.lastScale) < a.config.Cooldown {
+		return nil
+	}
+
+	load, err := a.config.Signal.Load()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case load > a.config.ScaleUpThreshold:
+		n := a.config.ScaleUpStep
+		if size+n > a.config.MaxWorkers {
+			n = a.config.MaxWorkers - size
+		}
+		return a.scale(n)
+	case load < a.config.ScaleDownThreshold:
+		n := a.config.ScaleDownStep
+		if size-n < a.config.MinWorkers {
+			n = size - a.config.MinWorkers
+		}
+		return a.scale(-n)
+	}
+	return nil
+}
+
+func (a *Autoscaler) scale(n int) error {
+	if n == 0 {
+		return nil
+	}
+
+	var err error
+	if n > 0 {
+		err = a.pool.Add(n, a.config.Arg)
+	} else {
+		err = a.pool.Reduce(-n)
+	}
+	a.lastScale = time.Now()
+	return err
+}
@@ -0,0 +1,128 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoscaler(t *testing.T) {
+	p, err := NewTaskPool(100)
+	assert.Nil(t, err)
+	defer p.Stop()
+
+	var load float64
+	a, err := NewAutoscaler(p, AutoscalerConfig{
+		MinWorkers:         1,
+		MaxWorkers:         4,
+		Signal:             GaugeSignal(func() float64 { return load }),
+		ScaleUpThreshold:   10,
+		ScaleDownThreshold: 1,
+		ScaleUpStep:        2,
+	})
+	assert.Nil(t, err)
+
+	// below min workers
+	assert.Nil(t, a.Scale())
+	assert.Equal(t, 1, p.Size())
+
+	load = 5
+	assert.Nil(t, a.Scale())
+	assert.Equal(t, 1, p.Size())
+
+	load = 100
+	assert.Nil(t, a.Scale())
+	assert.Equal(t, 3, p.Size())
+	assert.Nil(t, a.Scale())
+	assert.Equal(t, 4, p.Size())
+	assert.Nil(t, a.Scale())
+	assert.Equal(t, 4, p.Size())
+
+	load = 0
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, a.Scale())
+	}
+	assert.Equal(t, 1, p.Size())
+}
+
+func TestAutoscalerScaleDownRunningTasks(t *testing.T) {
+	p, err := NewTaskPool(10)
+	assert.Nil(t, err)
+	defer p.Stop()
+
+	load := 100.0
+	a, err := NewAutoscaler(p, AutoscalerConfig{
+		MaxWorkers:         2,
+		Signal:             GaugeSignal(func() float64 { return load }),
+		ScaleUpThreshold:   10,
+		ScaleDownThreshold: 1,
+		ScaleUpStep:        2,
+		ScaleDownStep:      2,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, a.Scale())
+	assert.Equal(t, 2, p.Size())
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var futures []*Future
+	for i := 0; i < 2; i++ {
+		f, err := p.Submit(context.Background(), func(ctx context.Context) error {
+			started <- struct{}{}
+			select {
+			case <-release:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		assert.Nil(t, err)
+		futures = append(futures, f)
+	}
+	<-started
+	<-started
+
+	// the load drops while the tasks are still running
+	load = 0
+	assert.Nil(t, a.Scale())
+	assert.Equal(t, 0, p.Size())
+
+	close(release)
+	for _, f := range futures {
+		assert.Nil(t, f.Wait(context.Background()))
+	}
+}
+
+func TestAutoscalerCooldown(t *testing.T) {
+	p, err := NewTaskPool(100)
+	assert.Nil(t, err)
+	defer p.Stop()
+
+	a, err := NewAutoscaler(p, AutoscalerConfig{
+		MinWorkers:       0,
+		MaxWorkers:       10,
+		Signal:           PendingSignal(p),
+		ScaleUpThreshold: 0,
+		Interval:         10 * time.Millisecond,
+		Cooldown:         time.Hour,
+	})
+	assert.Nil(t, err)
+
+	block := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		_, err = p.Submit(context.Background(), func(ctx context.Context) error {
+			<-block
+			return nil
+		})
+		assert.Nil(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	a.Run(ctx)
+	close(block)
+
+	assert.Equal(t, 1, p.Size())
+}
@@ -0,0 +1,287 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// FairTask is a task submitted on behalf of a tenant.
+type FairTask struct {
+	Tenant string
+	Weight int    // Weight updates the weight of the tenant when it is positive
+	Key    string // Key of the rate limit set by SetKeyRateLimit of the pool
+	Fn     TaskFunc
+}
+
+type TenantConfig struct {
+	Weight         int // share of the workers relative to other tenants, default 1
+	MaxConcurrency int // maximum number of running tasks of the tenant, 0 means unlimited
+}
+
+type tenant struct {
+	name    string
+	config  TenantConfig
+	queue   []*task
+	active  int
+	deficit int
+	space   chan struct{} // closed when a task of a full queue has been taken
+}
+
+func (t *tenant) capped() bool {
+	return t.config.MaxConcurrency > 0 && t.active >= t.config.MaxConcurrency
+}
+
+// FairScheduler shares the workers of a pool between tenants by deficit
+// round-robin, every tenant has its own bounded queue so that a noisy
+// tenant only blocks its own submissions.
+type FairScheduler struct {
+	pool      *AdjustablePool
+	queueSize int
+
+	lock    sync.Mutex
+	tenants map[string]*tenant
+	ring    []*tenant // tenants having queued tasks
+	cursor  int
+	queued  int
+	wake    chan struct{} // closed when a task may be dispatched
+}
+
+// NewFairScheduler creates a scheduler with a per-tenant queue of queueSize,
+// workers are managed through Pool.
+func NewFairScheduler(queueSize int) (*FairScheduler, error) {
+	if queueSize <= 0 {
+		return nil, errors.New("queue size must be positive")
+	}
+
+	s := &FairScheduler{
+		queueSize: queueSize,
+		tenants:   make(map[string]*tenant),
+		wake:      make(chan struct{}),
+	}
+
+	p := newAdjustablePool()
+	p.queue = s
+	p.factory = func(v interface{}) (Worker, error) {
+		return func(ctx context.Context) {
+			s.runTasks(ctx, v)
+		}, nil
+	}
+	s.pool = p
+
+	return s, nil
+}
+
+// Pool returns the pool running the tasks, use it to add, reduce or stop workers.
+func (s *FairScheduler) Pool() *AdjustablePool {
+	return s.pool
+}
+
+// SetTenant configures the tenant, it takes effect on the next dispatch.
+func (s *FairScheduler) SetTenant(name string, config TenantConfig) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.getTenant(name).config = normalizeTenantConfig(config)
+	s.broadcast()
+}
+
+func normalizeTenantConfig(config TenantConfig) TenantConfig {
+	if config.Weight <= 0 {
+		config.Weight = 1
+	}
+	if config.MaxConcurrency < 0 {
+		config.MaxConcurrency = 0
+	}
+	return config
+}
+
+func (s *FairScheduler) getTenant(name string) *tenant {
+	t, ok := s.tenants[name]
+	if !ok {
+		t = &tenant{
+			name:   name,
+			config: normalizeTenantConfig(TenantConfig{}),
+			space:  make(chan struct{}),
+		}
+		s.tenants[name] = t
+	}
+	return t
+}
+
+// Submit puts the task into the queue of its tenant,
+// blocks while the queue is full until ctx is done or the pool is stopped.
+// A task with Key waits for the rate limit of the key before it is queued.
+func (s *FairScheduler) Submit(ctx context.Context, ft FairTask) (*Future, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ft.Fn == nil {
+		return nil, errors.New("must provide function for task")
+	}
+	return s.pool.waitKeyLimit(ctx, ft.Key, func() (*Future, error) {
+		return s.submit(ctx, ft)
+	})
+}
+
+func (s *FairScheduler) submit(ctx context.Context, ft FairTask) (*Future, error) {
+	p := s.pool
+	p.submitLock.RLock()
+	defer p.submitLock.RUnlock()
+
+	t := &task{ctx: ctx, fn: ft.Fn, future: newFuture()}
+	for {
+		if p.isStopped() {
+			return nil, ErrPoolStopped
+		}
+
+		s.lock.Lock()
+		tn := s.getTenant(ft.Tenant)
+		if ft.Weight > 0 {
+			tn.config.Weight = ft.Weight
+		}
+		if len(tn.queue) < s.queueSize {
+			if len(tn.queue) == 0 {
+				s.ring = append(s.ring, tn)
+			}
+			tn.queue = append(tn.queue, t)
+			s.queued++
+			s.broadcast()
+			s.lock.Unlock()
+			return t.future, nil
+		}
+		space := tn.space
+		s.lock.Unlock()
+
+		select {
+		case <-space:
+		case <-p.done:
+			return nil, ErrPoolStopped
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// broadcast wakes up the idle workers, it must be called with the lock held.
+func (s *FairScheduler) broadcast() {
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// next picks a task by deficit round-robin, every pick of a tenant costs
+// one unit of the deficit which is refilled by its weight. It must be
+// called with the lock held and returns nil if nothing can be dispatched.
+func (s *FairScheduler) next() (*task, *tenant) {
+	for i := 0; i < len(s.ring); i++ {
+		if s.cursor >= len(s.ring) {
+			s.cursor = 0
+		}
+
+		tn := s.ring[s.cursor]
+		if tn.capped() {
+			s.cursor++
+			continue
+		}
+
+		if tn.deficit <= 0 {
+			tn.deficit = tn.config.Weight
+		}
+		tn.deficit--
+
+		t := tn.queue[0]
+		tn.queue[0] = nil
+		tn.queue = tn.queue[1:]
+		if len(tn.queue) == s.queueSize-1 {
+			close(tn.space)
+			tn.space = make(chan struct{})
+		}
+		tn.active++
+		s.queued--
+
+		if len(tn.queue) == 0 {
+			tn.deficit = 0
+			s.ring = append(s.ring[:s.cursor], s.ring[s.cursor+1:]...)
+		} else if tn.deficit <= 0 {
+			s.cursor++
+		}
+		return t, tn
+	}
+	return nil, nil
+}
+
+func (s *FairScheduler) runTasks(ctx context.Context, v interface{}) {
+	draining := Draining(ctx)
+	for ctx.Err() == nil {
+		s.lock.Lock()
+		t, tn := s.next()
+		wake := s.wake
+		queued := s.queued
+		s.lock.Unlock()
+
+		if t == nil {
+			drain := draining
+			if isClosed(draining) {
+				if queued == 0 {
+					return
+				}
+				// the queued tasks are waiting for the concurrency caps
+				drain = nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-wake:
+			case <-drain:
+			}
+			continue
+		}
+
+		s.pool.runTask(t, v)
+
+		s.lock.Lock()
+		tn.active--
+		if tn.config.MaxConcurrency > 0 {
+			s.broadcast()
+		}
+		s.lock.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Pending returns the number of queued tasks of the tenant.
+func (s *FairScheduler) Pending(tenant string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if tn, ok := s.tenants[tenant]; ok {
+		return len(tn.queue)
+	}
+	return 0
+}
+
+func (s *FairScheduler) pending() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.queued
+}
+
+func (s *FairScheduler) discard() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, tn := range s.ring {
+		for _, t := range tn.queue {
+			t.future.complete(ErrPoolStopped)
+		}
+		tn.queue = nil
+		tn.deficit = 0
+	}
+	s.ring = nil
+	s.cursor = 0
+	s.queued = 0
+}
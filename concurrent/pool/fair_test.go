@@ -0,0 +1,113 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFairSchedulerWeights(t *testing.T) {
+	s, err := NewFairScheduler(100)
+	assert.Nil(t, err)
+
+	var lock sync.Mutex
+	var order []string
+	record := func(tenant string) TaskFunc {
+		return func(ctx context.Context) error {
+			lock.Lock()
+			order = append(order, tenant)
+			lock.Unlock()
+			return nil
+		}
+	}
+
+	// submit before starting the single worker so that the order is deterministic
+	var futures []*Future
+	for i := 0; i < 30; i++ {
+		f, err := s.Submit(context.Background(), FairTask{Tenant: "noisy", Fn: record("noisy")})
+		assert.Nil(t, err)
+		futures = append(futures, f)
+	}
+	for i := 0; i < 5; i++ {
+		f, err := s.Submit(context.Background(), FairTask{Tenant: "quiet", Weight: 2, Fn: record("quiet")})
+		assert.Nil(t, err)
+		futures = append(futures, f)
+	}
+	assert.Equal(t, 35, s.Pool().Pending())
+
+	assert.Nil(t, s.Pool().Add(1, nil))
+	for _, f := range futures {
+		assert.Nil(t, f.Wait(context.Background()))
+	}
+
+	assert.Equal(t, []string{
+		"noisy", "quiet", "quiet",
+		"noisy", "quiet", "quiet",
+		"noisy", "quiet", "noisy",
+	}, order[:9])
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, s.Pool().Shutdown(ctx))
+}
+
+func TestFairSchedulerConcurrencyCap(t *testing.T) {
+	s, err := NewFairScheduler(100)
+	assert.Nil(t, err)
+	s.SetTenant("capped", TenantConfig{MaxConcurrency: 2})
+	assert.Nil(t, s.Pool().Add(8, nil))
+
+	var running, max int32
+	var futures []*Future
+	for i := 0; i < 20; i++ {
+		f, err := s.Submit(context.Background(), FairTask{Tenant: "capped", Fn: func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil
+		}})
+		assert.Nil(t, err)
+		futures = append(futures, f)
+	}
+
+	for _, f := range futures {
+		assert.Nil(t, f.Wait(context.Background()))
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&max))
+
+	s.Pool().Stop()
+	_, err = s.Submit(context.Background(), FairTask{Tenant: "capped", Fn: func(ctx context.Context) error { return nil }})
+	assert.Equal(t, ErrPoolStopped, err)
+}
+
+func TestFairSchedulerBackpressure(t *testing.T) {
+	s, err := NewFairScheduler(1)
+	assert.Nil(t, err)
+
+	noop := func(ctx context.Context) error { return nil }
+	_, err = s.Submit(context.Background(), FairTask{Tenant: "a", Fn: noop})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = s.Submit(ctx, FairTask{Tenant: "a", Fn: noop})
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// other tenants are not blocked by the full queue of tenant a
+	f, err := s.Submit(context.Background(), FairTask{Tenant: "b", Fn: noop})
+	assert.Nil(t, err)
+
+	s.Pool().Stop()
+	assert.Equal(t, ErrPoolStopped, f.Wait(context.Background()))
+	assert.Equal(t, 0, s.Pool().Pending())
+}
@@ -0,0 +1,192 @@
+package pool
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket which refills qps tokens per second up to burst.
+type Limiter struct {
+	lock   sync.Mutex
+	qps    float64
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a token bucket, a non-positive qps means no limit and
+// a non-positive burst defaults to max(1, qps).
+func NewLimiter(qps float64, burst int) *Limiter {
+	l := &Limiter{}
+	l.SetLimit(qps, burst)
+	return l
+}
+
+// SetLimit changes the rate and burst, the waiting callers keep their reservations.
+func (l *Limiter) SetLimit(qps float64, burst int) {
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(qps)))
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if l.last.IsZero() || l.qps <= 0 {
+		// the bucket was not limiting, it starts full
+		l.tokens = float64(burst)
+	} else {
+		l.advance(now)
+		l.tokens = math.Min(l.tokens, float64(burst))
+	}
+	l.qps = qps
+	l.burst = burst
+	l.last = now
+}
+
+// Limit returns the current rate and burst.
+func (l *Limiter) Limit() (qps float64, burst int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.qps, l.burst
+}
+
+func (l *Limiter) advance(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 && l.qps > 0 {
+		l.tokens = math.Min(float64(l.burst), l.tokens+elapsed.Seconds()*l.qps)
+	}
+	l.last = now
+}
+
+// reserve takes a token and returns how long the caller has to wait for it.
+func (l *Limiter) reserve() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.qps <= 0 {
+		return 0
+	}
+
+	l.advance(time.Now())
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.qps * float64(time.Second))
+}
+
+func (l *Limiter) cancel() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.qps > 0 {
+		l.tokens = math.Min(float64(l.burst), l.tokens+1)
+	}
+}
+
+// Allow takes a token if it is available right now.
+func (l *Limiter) Allow() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.qps <= 0 {
+		return true
+	}
+
+	l.advance(time.Now())
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return l.wait(ctx, nil)
+}
+
+func (l *Limiter) wait(ctx context.Context, stop <-chan struct{}) error {
+	d := l.reserve()
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	case <-stop:
+		l.cancel()
+		return context.Canceled
+	}
+}
+
+// Limiter returns the limiter shared by all task workers, long-running
+// workers may also Wait on it. It does not limit until SetRateLimit is called.
+func (p *AdjustablePool) Limiter() *Limiter {
+	return p.limiter
+}
+
+// SetRateLimit limits the tasks executed by all workers to qps with burst,
+// a non-positive qps removes the limit.
+func (p *AdjustablePool) SetRateLimit(qps float64, burst int) {
+	p.limiter.SetLimit(qps, burst)
+}
+
+// SetKeyRateLimit limits the tasks submitted with key by SubmitKey,
+// a non-positive qps removes the limit of the key.
+func (p *AdjustablePool) SetKeyRateLimit(key string, qps float64, burst int) {
+	p.limitLock.Lock()
+	defer p.limitLock.Unlock()
+
+	if qps <= 0 {
+		delete(p.keyLimiters, key)
+		return
+	}
+
+	if l, ok := p.keyLimiters[key]; ok {
+		l.SetLimit(qps, burst)
+		return
+	}
+	p.keyLimiters[key] = NewLimiter(qps, burst)
+}
+
+func (p *AdjustablePool) keyLimiter(key string) *Limiter {
+	p.limitLock.Lock()
+	defer p.limitLock.Unlock()
+	return p.keyLimiters[key]
+}
+
+// waitKeyLimit waits for the limiter of key before the task is queued,
+// so the tasks of a throttled key do not hold up the workers. The token
+// is returned by put if the task is not queued in the end.
+func (p *AdjustablePool) waitKeyLimit(ctx context.Context, key string, put func() (*Future, error)) (*Future, error) {
+	l := p.keyLimiter(key)
+	if key == "" || l == nil {
+		return put()
+	}
+
+	if err := l.wait(ctx, p.done); err != nil {
+		if p.isStopped() {
+			return nil, ErrPoolStopped
+		}
+		return nil, err
+	}
+
+	f, err := put()
+	if err != nil {
+		l.cancel()
+	}
+	return f, err
+}
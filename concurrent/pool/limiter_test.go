@@ -0,0 +1,110 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(100, 5)
+	for i := 0; i < 5; i++ {
+		assert.True(t, l.Allow())
+	}
+	assert.False(t, l.Allow())
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, l.Wait(context.Background()))
+	}
+	assert.True(t, time.Since(start) >= 80*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	l.SetLimit(0.1, 1)
+	assert.Equal(t, context.DeadlineExceeded, l.Wait(ctx))
+
+	l.SetLimit(0, 0)
+	assert.True(t, l.Allow())
+	assert.Nil(t, l.Wait(context.Background()))
+
+	// the bucket starts full once it limits again
+	l.SetLimit(100, 3)
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.Allow())
+	}
+	assert.False(t, l.Allow())
+}
+
+func TestPoolRateLimit(t *testing.T) {
+	p, err := NewTaskPool(100)
+	assert.Nil(t, err)
+	defer p.Stop()
+
+	assert.Nil(t, p.Add(8, nil))
+	p.SetRateLimit(200, 1)
+	p.SetKeyRateLimit("hbase", 50, 1)
+
+	noop := func(ctx context.Context) error { return nil }
+
+	start := time.Now()
+	var futures []*Future
+	for i := 0; i < 20; i++ {
+		f, err := p.Submit(context.Background(), noop)
+		assert.Nil(t, err)
+		futures = append(futures, f)
+	}
+	for _, f := range futures {
+		assert.Nil(t, f.Wait(context.Background()))
+	}
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+
+	start = time.Now()
+	futures = futures[:0]
+	for i := 0; i < 6; i++ {
+		f, err := p.SubmitKey(context.Background(), "hbase", noop)
+		assert.Nil(t, err)
+		futures = append(futures, f)
+	}
+	for _, f := range futures {
+		assert.Nil(t, f.Wait(context.Background()))
+	}
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+
+	qps, burst := p.Limiter().Limit()
+	assert.Equal(t, 200.0, qps)
+	assert.Equal(t, 1, burst)
+}
+
+func TestPoolKeyRateLimitNotBlockingWorkers(t *testing.T) {
+	p, err := NewTaskPool(10)
+	assert.Nil(t, err)
+	defer p.Stop()
+
+	assert.Nil(t, p.Add(1, nil))
+	p.SetKeyRateLimit("slow", 1, 1)
+
+	noop := func(ctx context.Context) error { return nil }
+	_, err = p.SubmitKey(context.Background(), "slow", noop)
+	assert.Nil(t, err)
+
+	// the next task of the key waits for a second before it is queued
+	ctx, cancel := context.WithCancel(context.Background())
+	throttled := make(chan error, 1)
+	go func() {
+		_, err := p.SubmitKey(ctx, "slow", noop)
+		throttled <- err
+	}()
+
+	// the other tasks are not held up by the throttled key
+	f, err := p.Submit(context.Background(), noop)
+	assert.Nil(t, err)
+	wait, cancelWait := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelWait()
+	assert.Nil(t, f.Wait(wait))
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-throttled)
+}
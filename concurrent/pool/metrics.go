@@ -0,0 +1,165 @@
+package pool
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics receives the events of the pool, the gauges such as running workers
+// and queue depth are read from the pool directly.
+type Metrics interface {
+	WorkerStarted()
+	WorkerStopped()
+	WorkerPanicked()
+	TaskDone(latency time.Duration)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) WorkerStarted()           {}
+func (nopMetrics) WorkerStopped()           {}
+func (nopMetrics) WorkerPanicked()          {}
+func (nopMetrics) TaskDone(_ time.Duration) {}
+
+type metricsHolder struct {
+	Metrics
+}
+
+// SetMetrics sets the metrics of the pool, nil disables metrics.
+func (p *AdjustablePool) SetMetrics(m Metrics) {
+	if m == nil {
+		m = nopMetrics{}
+	}
+	p.metrics.Store(metricsHolder{m})
+}
+
+func (p *AdjustablePool) getMetrics() Metrics {
+	if h, ok := p.metrics.Load().(metricsHolder); ok {
+		return h.Metrics
+	}
+	return nopMetrics{}
+}
+
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// PrometheusMetrics implements Metrics and serves them in the prometheus
+// text exposition format.
+type PrometheusMetrics struct {
+	pool   *AdjustablePool
+	prefix string
+
+	started  uint64
+	stopped  uint64
+	panicked uint64
+
+	lock    sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// NewPrometheusMetrics creates the metrics of p whose names are prefixed by prefix,
+// buckets are the upper bounds of the task latency histogram in seconds, the
+// +Inf bucket is always written so the infinite and duplicated bounds are dropped.
+// It must be installed with p.SetMetrics.
+func NewPrometheusMetrics(p *AdjustablePool, prefix string, buckets []float64) (*PrometheusMetrics, error) {
+	if prefix == "" {
+		prefix = "pool"
+	}
+	if !metricNameRegexp.MatchString(prefix) {
+		return nil, errors.New("invalid prefix of prometheus metrics: " + prefix)
+	}
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+
+	var bounds []float64
+	for _, upper := range buckets {
+		if !math.IsInf(upper, 0) && !math.IsNaN(upper) {
+			bounds = append(bounds, upper)
+		}
+	}
+	sort.Float64s(bounds)
+	buckets = bounds[:0]
+	for i, upper := range bounds {
+		if i == 0 || upper != bounds[i-1] {
+			buckets = append(buckets, upper)
+		}
+	}
+
+	return &PrometheusMetrics{
+		pool:    p,
+		prefix:  prefix,
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}, nil
+}
+
+func (m *PrometheusMetrics) WorkerStarted()  { atomic.AddUint64(&m.started, 1) }
+func (m *PrometheusMetrics) WorkerStopped()  { atomic.AddUint64(&m.stopped, 1) }
+func (m *PrometheusMetrics) WorkerPanicked() { atomic.AddUint64(&m.panicked, 1) }
+
+func (m *PrometheusMetrics) TaskDone(latency time.Duration) {
+	v := latency.Seconds()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i, upper := range m.buckets {
+		if v <= upper {
+			m.counts[i]++
+		}
+	}
+	m.sum += v
+	m.count++
+}
+
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	m.writeMetric(bw, "workers_running", "gauge", "Number of running workers.", float64(m.pool.Running()))
+	m.writeMetric(bw, "workers_exiting", "gauge", "Number of reduced workers which have not exited yet.", float64(m.pool.Exiting()))
+	m.writeMetric(bw, "task_queue_depth", "gauge", "Number of tasks waiting in the queue.", float64(m.pool.Pending()))
+	m.writeMetric(bw, "workers_started_total", "counter", "Number of started workers.", float64(atomic.LoadUint64(&m.started)))
+	m.writeMetric(bw, "workers_stopped_total", "counter", "Number of exited workers.", float64(atomic.LoadUint64(&m.stopped)))
+	m.writeMetric(bw, "workers_panicked_total", "counter", "Number of panics recovered from workers and tasks.", float64(atomic.LoadUint64(&m.panicked)))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	name := m.prefix + "_task_latency_seconds"
+	fmt.Fprintf(bw, "# HELP %s Latency of the executed tasks.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+	for i, upper := range m.buckets {
+		fmt.Fprintf(bw, "%s_bucket{le=\"%s\"} %d\n", name, formatFloat(upper), m.counts[i])
+	}
+	fmt.Fprintf(bw, "%s_bucket{le=\"+Inf\"} %d\n", name, m.count)
+	fmt.Fprintf(bw, "%s_sum %s\n", name, formatFloat(m.sum))
+	fmt.Fprintf(bw, "%s_count %d\n", name, m.count)
+}
+
+func (m *PrometheusMetrics) writeMetric(w *bufio.Writer, name, typ, help string, v float64) {
+	name = m.prefix + "_" + name
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(v))
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
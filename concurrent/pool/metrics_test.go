@@ -0,0 +1,51 @@
+package pool
+
+import (
+	"context"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	p, err := NewTaskPool(10)
+	assert.Nil(t, err)
+
+	m, err := NewPrometheusMetrics(p, "ingest", []float64{1, 0.1, math.Inf(1), 1})
+	assert.Nil(t, err)
+	p.SetMetrics(m)
+
+	assert.Nil(t, p.Add(2, nil))
+	for i := 0; i < 3; i++ {
+		f, err := p.Submit(context.Background(), func(ctx context.Context) error { return nil })
+		assert.Nil(t, err)
+		assert.Nil(t, f.Wait(context.Background()))
+	}
+	f, err := p.Submit(context.Background(), func(ctx context.Context) error { panic("boom") })
+	assert.Nil(t, err)
+	assert.NotNil(t, f.Wait(context.Background()))
+
+	p.Stop()
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	t.Log(body)
+
+	assert.Contains(t, body, "# TYPE ingest_workers_running gauge\ningest_workers_running 0\n")
+	assert.Contains(t, body, "ingest_task_queue_depth 0\n")
+	assert.Contains(t, body, "ingest_workers_started_total 2\n")
+	assert.Contains(t, body, "ingest_workers_stopped_total 2\n")
+	assert.Contains(t, body, "ingest_workers_panicked_total 1\n")
+	assert.Contains(t, body, "ingest_task_latency_seconds_bucket{le=\"0.1\"} 4\n")
+	assert.Contains(t, body, "ingest_task_latency_seconds_bucket{le=\"+Inf\"} 4\n")
+	assert.Contains(t, body, "ingest_task_latency_seconds_count 4\n")
+	assert.Equal(t, 1, strings.Count(body, "le=\"+Inf\""))
+	assert.Equal(t, 1, strings.Count(body, "le=\"1\""))
+
+	_, err = NewPrometheusMetrics(p, "ingest-pool", nil)
+	assert.NotNil(t, err)
+}
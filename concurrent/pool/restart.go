@@ -0,0 +1,99 @@
+package pool
+
+import (
+	"fmt"
+	"time"
+)
+
+type RestartMode int
+
+const (
+	// RestartNever lets the worker go once it returned or panicked.
+	RestartNever RestartMode = iota
+	// RestartOnFailure restarts the worker after it panicked.
+	RestartOnFailure
+	// RestartAlways restarts the worker whenever it exits before being stopped.
+	RestartAlways
+)
+
+// RestartPolicy decides whether a worker exiting on its own is started again,
+// the WorkerFactory is called again with the same argument on every restart
+// and the worker is let go if the factory fails.
+type RestartPolicy struct {
+	Mode           RestartMode
+	InitialBackoff time.Duration // default 100ms, doubled after every restart
+	MaxBackoff     time.Duration // default 30s
+	MaxRestarts    int           // 0 means unlimited
+}
+
+func (r RestartPolicy) shouldRestart(panicked bool, restarts int) bool {
+	if r.MaxRestarts > 0 && restarts >= r.MaxRestarts {
+		return false
+	}
+
+	switch r.Mode {
+	case RestartOnFailure:
+		return panicked
+	case RestartAlways:
+		return true
+	}
+	return false
+}
+
+func (r RestartPolicy) backoff(restarts int) time.Duration {
+	backoff, max := r.InitialBackoff, r.MaxBackoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+
+	for i := 0; i < restarts && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return backoff
+}
+
+// PanicHandler is called with the argument the worker was created from,
+// the recovered value and the stack of the panicking goroutine.
+type PanicHandler func(v interface{}, recovered interface{}, stack []byte)
+
+// PanicError is the error of a task which panicked.
+type PanicError struct {
+	Recovered interface{}
+	Stack     []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Recovered)
+}
+
+// SetRestartPolicy sets the restart policy of the workers added afterwards.
+func (p *AdjustablePool) SetRestartPolicy(policy RestartPolicy) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.restart = policy
+}
+
+// SetPanicHandler sets the handler of the panics recovered from workers and tasks.
+func (p *AdjustablePool) SetPanicHandler(h PanicHandler) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.panicHandler = h
+}
+
+func (p *AdjustablePool) handlePanic(v interface{}, recovered interface{}, stack []byte) {
+	p.getMetrics().WorkerPanicked()
+
+	p.lock.Lock()
+	h := p.panicHandler
+	p.lock.Unlock()
+
+	if h != nil {
+		h(v, recovered, stack)
+	}
+}
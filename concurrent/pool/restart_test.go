@@ -0,0 +1,100 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPanicRecovery(t *testing.T) {
+	var panics int32
+	p, err := NewAdjustablePool(func(v interface{}) (Worker, error) {
+		return func(ctx context.Context) {
+			panic(v)
+		}, nil
+	})
+	assert.Nil(t, err)
+
+	p.SetPanicHandler(func(v interface{}, recovered interface{}, stack []byte) {
+		assert.Equal(t, "decoder", v)
+		assert.Equal(t, "decoder", recovered)
+		assert.NotEmpty(t, stack)
+		atomic.AddInt32(&panics, 1)
+	})
+	p.SetRestartPolicy(RestartPolicy{
+		Mode:           RestartOnFailure,
+		InitialBackoff: time.Millisecond,
+		MaxRestarts:    3,
+	})
+
+	assert.Nil(t, p.Add(1, "decoder"))
+
+	assert.Eventually(t, func() bool {
+		return p.Size() == 0 && p.Running() == 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&panics))
+	assert.Equal(t, 0, p.Exiting())
+
+	p.Stop()
+}
+
+func TestWorkerRestartAlways(t *testing.T) {
+	var runs, created int32
+	p, err := NewAdjustablePool(func(v interface{}) (Worker, error) {
+		atomic.AddInt32(&created, 1)
+		var ran bool
+		return func(ctx context.Context) {
+			// every restart runs a fresh worker
+			assert.False(t, ran)
+			ran = true
+			atomic.AddInt32(&runs, 1)
+		}, nil
+	})
+	assert.Nil(t, err)
+
+	p.SetRestartPolicy(RestartPolicy{Mode: RestartAlways, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	assert.Nil(t, p.Add(1, nil))
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&runs) > 5
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, p.Size())
+
+	p.Stop()
+	assert.Equal(t, 0, p.Running())
+	assert.Equal(t, 0, p.Exiting())
+}
+
+func TestTaskPanic(t *testing.T) {
+	p, err := NewTaskPool(1)
+	assert.Nil(t, err)
+	defer p.Stop()
+
+	var panics int32
+	p.SetPanicHandler(func(v interface{}, recovered interface{}, stack []byte) {
+		atomic.AddInt32(&panics, 1)
+	})
+	assert.Nil(t, p.Add(1, nil))
+
+	f, err := p.Submit(context.Background(), func(ctx context.Context) error {
+		panic("boom")
+	})
+	assert.Nil(t, err)
+
+	err = f.Wait(context.Background())
+	pe, ok := err.(*PanicError)
+	assert.True(t, ok)
+	assert.Equal(t, "boom", pe.Recovered)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&panics))
+	assert.Equal(t, 1, p.Size())
+}
+
+func TestRestartBackoff(t *testing.T) {
+	r := RestartPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, r.backoff(0))
+	assert.Equal(t, 4*time.Second, r.backoff(2))
+	assert.Equal(t, 5*time.Second, r.backoff(10))
+}
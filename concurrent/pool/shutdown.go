@@ -0,0 +1,88 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type drainingKey struct{}
+
+// Draining returns a channel that is closed when the pool of the worker
+// owning ctx starts shutting down gracefully. Workers should finish their
+// current unit of work and return, the ctx itself is canceled only when
+// the shutdown deadline is exceeded.
+func Draining(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(drainingKey{}).(chan struct{})
+	return ch
+}
+
+// ShutdownError lists the workers that did not exit before the deadline,
+// they have been canceled but may still be running when it is returned.
+type ShutdownError struct {
+	WorkerIDs []uint64
+}
+
+func (e *ShutdownError) Error() string {
+	ids := make([]string, 0, len(e.WorkerIDs))
+	for _, id := range e.WorkerIDs {
+		ids = append(ids, fmt.Sprint(id))
+	}
+	return fmt.Sprintf("%d workers did not exit before the deadline: %s",
+		len(e.WorkerIDs), strings.Join(ids, ", "))
+}
+
+// Shutdown stops accepting tasks and signals the workers through Draining,
+// the queued tasks are still executed by task workers. When ctx is done
+// before every worker exited, the remaining workers and the running tasks
+// are canceled and a ShutdownError listing the workers is returned without
+// waiting for them any longer, so a worker ignoring its ctx may keep running
+// after Shutdown returned, Exiting reports how many of them are still running.
+func (p *AdjustablePool) Shutdown(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if !p.markStopped() {
+		return ErrPoolStopped
+	}
+
+	// wait for the in-flight submissions so that nothing is enqueued
+	// after the task workers found the queue empty
+	p.submitLock.Lock()
+	close(p.draining)
+	p.submitLock.Unlock()
+
+	exited := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(exited)
+	}()
+
+	select {
+	case <-exited:
+		p.discardTasks()
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.lock.Lock()
+	p.abortTasks()
+	p.reduce(len(p.workers))
+	err := &ShutdownError{}
+	for w := range p.alive {
+		err.WorkerIDs = append(err.WorkerIDs, w.id)
+	}
+	p.lock.Unlock()
+
+	p.discardTasks()
+
+	if len(err.WorkerIDs) == 0 {
+		return nil
+	}
+	sort.Slice(err.WorkerIDs, func(i, j int) bool {
+		return err.WorkerIDs[i] < err.WorkerIDs[j]
+	})
+	return err
+}
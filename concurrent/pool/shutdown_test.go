@@ -0,0 +1,68 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownDrainsTasks(t *testing.T) {
+	p, err := NewTaskPool(100)
+	assert.Nil(t, err)
+
+	var count int32
+	for i := 0; i < 50; i++ {
+		_, err := p.Submit(context.Background(), func(ctx context.Context) error {
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&count, 1)
+			return nil
+		})
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, p.Add(4, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, p.Shutdown(ctx))
+	assert.Equal(t, int32(50), atomic.LoadInt32(&count))
+	assert.Equal(t, 0, p.Running())
+
+	_, err = p.Submit(context.Background(), func(ctx context.Context) error { return nil })
+	assert.Equal(t, ErrPoolStopped, err)
+	assert.Equal(t, ErrPoolStopped, p.Shutdown(ctx))
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	p, err := NewAdjustablePool(func(v interface{}) (Worker, error) {
+		if v == "stubborn" {
+			return func(ctx context.Context) {
+				<-ctx.Done()
+			}, nil
+		}
+		return func(ctx context.Context) {
+			select {
+			case <-ctx.Done():
+			case <-Draining(ctx):
+			}
+		}, nil
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, p.Add(2, "polite"))
+	assert.Nil(t, p.Add(1, "stubborn"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = p.Shutdown(ctx)
+	se, ok := err.(*ShutdownError)
+	assert.True(t, ok)
+	assert.Equal(t, []uint64{3}, se.WorkerIDs)
+
+	// the canceled worker exits after Shutdown returned
+	assert.Eventually(t, func() bool {
+		return p.Running() == 0 && p.Exiting() == 0
+	}, time.Second, time.Millisecond)
+}
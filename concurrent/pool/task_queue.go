@@ -0,0 +1,287 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"time"
+)
+
+var (
+	ErrPoolStopped = errors.New("pool is stopped")
+	ErrQueueFull   = errors.New("task queue is full")
+	ErrNoTaskQueue = errors.New("pool has no task queue, create it with NewTaskPool")
+)
+
+// TaskFunc is a discrete unit of work submitted to a task pool.
+type TaskFunc func(ctx context.Context) error
+
+// Future is the handle of a submitted task.
+type Future struct {
+	done chan struct{}
+	err  error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+func (f *Future) complete(err error) {
+	f.err = err
+	close(f.done)
+}
+
+// Done is closed when the task has finished or has been discarded.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Err returns the error of the task, it is only meaningful after Done is closed.
+func (f *Future) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+	}
+	return nil
+}
+
+// Wait blocks until the task finished or ctx is done.
+func (f *Future) Wait(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type task struct {
+	ctx    context.Context
+	fn     TaskFunc
+	future *Future
+}
+
+// run executes the task with a context that is canceled when either the
+// submitter's context is done or the running tasks are aborted. A panic of
+// the task is recovered and returned as PanicError.
+func (t *task) run(abort <-chan struct{}) (pe *PanicError, err error) {
+	if err := t.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(t.ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-abort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			pe = &PanicError{Recovered: r, Stack: debug.Stack()}
+			err = pe
+		}
+	}()
+
+	return nil, t.fn(ctx)
+}
+
+// NewTaskPool creates a pool whose workers drain a bounded task queue,
+// workers are started with Add and stopped with Reduce as usual. A reduced
+// worker finishes its running task before it exits, the running tasks are
+// only canceled by Stop or by Shutdown once its deadline is exceeded.
+func NewTaskPool(queueSize int) (*AdjustablePool, error) {
+	if queueSize <= 0 {
+		return nil, errors.New("queue size must be positive")
+	}
+
+	p := newAdjustablePool()
+	p.tasks = make(chan *task, queueSize)
+	p.factory = func(v interface{}) (Worker, error) {
+		return func(ctx context.Context) {
+			p.runTasks(ctx, v)
+		}, nil
+	}
+
+	return p, nil
+}
+
+// runTasks drains the task queue until the worker is stopped,
+// or until the queue is empty once the pool is draining.
+func (p *AdjustablePool) runTasks(ctx context.Context, v interface{}) {
+	draining := Draining(ctx)
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-p.tasks:
+			p.runTask(t, v)
+		case <-draining:
+			for ctx.Err() == nil {
+				select {
+				case t := <-p.tasks:
+					p.runTask(t, v)
+				default:
+					return
+				}
+			}
+			return
+		}
+	}
+}
+
+// runTask runs t to the end even if the worker is reduced meanwhile.
+func (p *AdjustablePool) runTask(t *task, v interface{}) {
+	if err := p.limiter.wait(t.ctx, p.abort); err != nil {
+		t.future.complete(err)
+		return
+	}
+
+	start := time.Now()
+	pe, err := t.run(p.abort)
+	p.getMetrics().TaskDone(time.Since(start))
+
+	if pe != nil {
+		p.handlePanic(v, pe.Recovered, pe.Stack)
+	}
+	t.future.complete(err)
+}
+
+// Submit puts fn into the task queue, blocks while the queue is full until
+// ctx is done or the pool is stopped.
+func (p *AdjustablePool) Submit(ctx context.Context, fn TaskFunc) (*Future, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return p.enqueue(ctx, fn, true, ctx.Done())
+}
+
+// SubmitKey is like Submit, the task is additionally limited by the rate
+// limit of key set by SetKeyRateLimit. It blocks until the limit of key
+// allows the task before putting it into the queue.
+func (p *AdjustablePool) SubmitKey(ctx context.Context, key string, fn TaskFunc) (*Future, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if fn == nil {
+		return nil, errors.New("must provide function for task")
+	}
+	if p.tasks == nil {
+		return nil, ErrNoTaskQueue
+	}
+	return p.waitKeyLimit(ctx, key, func() (*Future, error) {
+		return p.enqueue(ctx, fn, true, ctx.Done())
+	})
+}
+
+// TrySubmit puts fn into the task queue without blocking,
+// returns ErrQueueFull if the queue is full.
+func (p *AdjustablePool) TrySubmit(ctx context.Context, fn TaskFunc) (*Future, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return p.enqueue(ctx, fn, false, nil)
+}
+
+// SubmitTimeout is like Submit but gives up with ErrQueueFull after timeout.
+// The timeout only bounds the waiting for queue space, not the task itself.
+func (p *AdjustablePool) SubmitTimeout(ctx context.Context, timeout time.Duration, fn TaskFunc) (*Future, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return p.enqueue(ctx, fn, true, waitCtx.Done())
+}
+
+// enqueue waits for the queue space until wait is closed if block is true.
+func (p *AdjustablePool) enqueue(ctx context.Context, fn TaskFunc, block bool, wait <-chan struct{}) (*Future, error) {
+	if fn == nil {
+		return nil, errors.New("must provide function for task")
+	}
+	if p.tasks == nil {
+		return nil, ErrNoTaskQueue
+	}
+
+	p.submitLock.RLock()
+	defer p.submitLock.RUnlock()
+
+	if p.isStopped() {
+		return nil, ErrPoolStopped
+	}
+
+	t := &task{ctx: ctx, fn: fn, future: newFuture()}
+
+	select {
+	case p.tasks <- t:
+		return t.future, nil
+	default:
+		if !block {
+			return nil, ErrQueueFull
+		}
+	}
+
+	select {
+	case p.tasks <- t:
+		return t.future, nil
+	case <-p.done:
+		return nil, ErrPoolStopped
+	case <-wait:
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrQueueFull
+	}
+}
+
+// Pending returns the number of tasks waiting in the queue.
+func (p *AdjustablePool) Pending() int {
+	n := len(p.tasks)
+	if p.queue != nil {
+		n += p.queue.pending()
+	}
+	return n
+}
+
+// QueueSize returns the capacity of the task queue.
+func (p *AdjustablePool) QueueSize() int {
+	return cap(p.tasks)
+}
+
+// discardTasks completes every queued task with ErrPoolStopped,
+// it must be called after the pool has been stopped.
+func (p *AdjustablePool) discardTasks() {
+	p.submitLock.Lock()
+	defer p.submitLock.Unlock()
+
+	if p.queue != nil {
+		p.queue.discard()
+	}
+
+	for {
+		select {
+		case t := <-p.tasks:
+			t.future.complete(ErrPoolStopped)
+		default:
+			return
+		}
+	}
+}
+
+// taskQueue is a task queue drained by workers other than the channel
+// based one, such as the FairScheduler.
+type taskQueue interface {
+	pending() int
+	discard()
+}
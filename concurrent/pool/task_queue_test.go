@@ -0,0 +1,118 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskPool(t *testing.T) {
+	p, err := NewTaskPool(10)
+	assert.Nil(t, err)
+
+	err = p.Add(4, nil)
+	assert.Nil(t, err)
+
+	var count int32
+	var futures []*Future
+	for i := 0; i < 100; i++ {
+		f, err := p.Submit(context.Background(), func(ctx context.Context) error {
+			atomic.AddInt32(&count, 1)
+			return nil
+		})
+		assert.Nil(t, err)
+		futures = append(futures, f)
+	}
+
+	for _, f := range futures {
+		assert.Nil(t, f.Wait(context.Background()))
+	}
+	assert.Equal(t, int32(100), atomic.LoadInt32(&count))
+
+	taskErr := errors.New("task error")
+	f, err := p.Submit(context.Background(), func(ctx context.Context) error {
+		return taskErr
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, taskErr, f.Wait(context.Background()))
+
+	p.Stop()
+
+	_, err = p.Submit(context.Background(), func(ctx context.Context) error { return nil })
+	assert.Equal(t, ErrPoolStopped, err)
+}
+
+func TestTaskPoolBackpressure(t *testing.T) {
+	p, err := NewTaskPool(1)
+	assert.Nil(t, err)
+
+	noop := func(ctx context.Context) error { return nil }
+
+	// no workers are running, the queue fills up
+	_, err = p.TrySubmit(context.Background(), noop)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, p.Pending())
+
+	_, err = p.TrySubmit(context.Background(), noop)
+	assert.Equal(t, ErrQueueFull, err)
+
+	_, err = p.SubmitTimeout(context.Background(), 10*time.Millisecond, noop)
+	assert.Equal(t, ErrQueueFull, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = p.Submit(ctx, noop)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	p.Stop()
+	assert.Equal(t, 0, p.Pending())
+
+	np, err := NewAdjustablePool(func(interface{}) (Worker, error) { return nil, nil })
+	assert.Nil(t, err)
+	_, err = np.Submit(context.Background(), noop)
+	assert.Equal(t, ErrNoTaskQueue, err)
+}
+
+func TestTaskPoolReduceRunningTask(t *testing.T) {
+	p, err := NewTaskPool(10)
+	assert.Nil(t, err)
+	assert.Nil(t, p.Add(1, nil))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocked := func(ctx context.Context) error {
+		close(started)
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// the reduced worker finishes its running task
+	f, err := p.Submit(context.Background(), blocked)
+	assert.Nil(t, err)
+	<-started
+	assert.Nil(t, p.Reduce(1))
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	assert.Nil(t, f.Wait(context.Background()))
+	assert.Eventually(t, func() bool {
+		return p.Running() == 0
+	}, time.Second, time.Millisecond)
+
+	// Stop cancels the running task
+	started = make(chan struct{})
+	release = make(chan struct{})
+	assert.Nil(t, p.Add(1, nil))
+	f, err = p.Submit(context.Background(), blocked)
+	assert.Nil(t, err)
+	<-started
+	p.Stop()
+	assert.Equal(t, context.Canceled, f.Wait(context.Background()))
+}
@@ -0,0 +1,113 @@
+package pool
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+type WorkerState int32
+
+const (
+	WorkerStarting WorkerState = iota
+	WorkerRunning
+	WorkerRestarting // waiting for the restart backoff
+	WorkerExiting    // stopped but not exited yet
+)
+
+func (s WorkerState) String() string {
+	switch s {
+	case WorkerStarting:
+		return "starting"
+	case WorkerRunning:
+		return "running"
+	case WorkerRestarting:
+		return "restarting"
+	case WorkerExiting:
+		return "exiting"
+	}
+	return "unknown"
+}
+
+// WorkerInfo describes a worker of the pool.
+type WorkerInfo struct {
+	ID        uint64
+	Arg       interface{} // Arg is the argument the worker was created from
+	StartedAt time.Time   // StartedAt is reset whenever the worker restarts
+	Restarts  int
+	State     WorkerState
+}
+
+func (w *ContextWithWorker) setState(old, new WorkerState) bool {
+	return atomic.CompareAndSwapInt32(&w.state, int32(old), int32(new))
+}
+
+func (w *ContextWithWorker) Info() WorkerInfo {
+	return WorkerInfo{
+		ID:        w.id,
+		Arg:       w.arg,
+		StartedAt: w.startedAt.Load().(time.Time),
+		Restarts:  int(atomic.LoadInt32(&w.restarts)),
+		State:     WorkerState(atomic.LoadInt32(&w.state)),
+	}
+}
+
+// Snapshot returns the workers which have not exited yet ordered by ID,
+// including the reduced ones which are still exiting.
+func (p *AdjustablePool) Snapshot() []WorkerInfo {
+	p.lock.Lock()
+	infos := make([]WorkerInfo, 0, len(p.alive))
+	for w := range p.alive {
+		infos = append(infos, w.Info())
+	}
+	p.lock.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// ReduceWhere stops the workers matching the predicate,
+// returns the number of stopped workers. The predicate is called without
+// holding the lock of the pool, so it may call the other methods of the pool.
+func (p *AdjustablePool) ReduceWhere(predicate func(WorkerInfo) bool) int {
+	p.lock.Lock()
+	workers := append([]*ContextWithWorker(nil), p.workers...)
+	p.lock.Unlock()
+
+	matched := make(map[*ContextWithWorker]bool)
+	for _, w := range workers {
+		if predicate(w.Info()) {
+			matched[w] = true
+		}
+	}
+	if len(matched) == 0 {
+		return 0
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.isStopped() {
+		return 0
+	}
+
+	// the workers exited or reduced meanwhile are no longer in p.workers
+	var n int
+	workers = p.workers[:0]
+	for _, w := range p.workers {
+		if matched[w] {
+			w.Stop()
+			atomic.AddInt32(&p.exiting, 1)
+			n++
+			continue
+		}
+		workers = append(workers, w)
+	}
+	for i := len(workers); i < len(p.workers); i++ {
+		p.workers[i] = nil
+	}
+	p.workers = workers
+	return n
+}
@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotAndReduceWhere(t *testing.T) {
+	p, err := NewAdjustablePool(func(v interface{}) (Worker, error) {
+		return func(ctx context.Context) {
+			<-ctx.Done()
+		}, nil
+	})
+	assert.Nil(t, err)
+	defer p.Stop()
+
+	for shard := 0; shard < 4; shard++ {
+		assert.Nil(t, p.Add(1, shard))
+	}
+
+	assert.Eventually(t, func() bool {
+		return p.Running() == 4
+	}, time.Second, time.Millisecond)
+
+	infos := p.Snapshot()
+	assert.Len(t, infos, 4)
+	for i, info := range infos {
+		assert.Equal(t, uint64(i+1), info.ID)
+		assert.Equal(t, i, info.Arg)
+		assert.Equal(t, WorkerRunning, info.State)
+		assert.False(t, info.StartedAt.IsZero())
+	}
+
+	// the predicate may call the pool
+	n := p.ReduceWhere(func(info WorkerInfo) bool {
+		return p.Size() == 4 && len(p.Snapshot()) == 4 && info.Arg.(int)%2 == 0
+	})
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 2, p.Size())
+
+	assert.Eventually(t, func() bool {
+		return len(p.Snapshot()) == 2
+	}, time.Second, time.Millisecond)
+
+	var shards []interface{}
+	for _, info := range p.Snapshot() {
+		shards = append(shards, info.Arg)
+	}
+	assert.Equal(t, []interface{}{1, 3}, shards)
+}
+
+func TestSnapshotRestartedAt(t *testing.T) {
+	p, err := NewAdjustablePool(func(v interface{}) (Worker, error) {
+		return func(ctx context.Context) {
+			time.Sleep(20 * time.Millisecond)
+		}, nil
+	})
+	assert.Nil(t, err)
+	defer p.Stop()
+
+	p.SetRestartPolicy(RestartPolicy{Mode: RestartAlways, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	assert.Nil(t, p.Add(1, nil))
+	first := p.Snapshot()[0]
+
+	assert.Eventually(t, func() bool {
+		return p.Snapshot()[0].Restarts > 0
+	}, time.Second, time.Millisecond)
+	assert.True(t, p.Snapshot()[0].StartedAt.After(first.StartedAt))
+}
@@ -0,0 +1,164 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Policy describes how an operation is retried, the zero value retries with
+// exponential backoff until ctx is done.
+type Policy struct {
+	InitialInterval time.Duration // default 100ms
+	MaxInterval     time.Duration // default 10s
+	Multiplier      float64       // default 2
+	// Jitter randomizes every interval by ±Jitter of itself, between 0 and 1.
+	Jitter float64
+	// MaxAttempts is the maximum number of calls including the first one, 0 means unlimited.
+	MaxAttempts int
+	// MaxElapsedTime stops retrying once it has elapsed since the first call, 0 means unlimited.
+	MaxElapsedTime time.Duration
+	// Retryable classifies the errors, default every error except context
+	// errors and the ones wrapped by Permanent is retryable.
+	Retryable func(error) bool
+}
+
+// Constant retries attempts times at a fixed interval.
+func Constant(interval time.Duration, attempts int) Policy {
+	return Policy{
+		InitialInterval: interval,
+		MaxInterval:     interval,
+		Multiplier:      1,
+		MaxAttempts:     attempts,
+	}
+}
+
+// Exponential retries attempts times with exponential backoff and 50% jitter.
+func Exponential(initial, max time.Duration, attempts int) Policy {
+	return Policy{
+		InitialInterval: initial,
+		MaxInterval:     max,
+		Multiplier:      2,
+		Jitter:          0.5,
+		MaxAttempts:     attempts,
+	}
+}
+
+var (
+	randLock sync.Mutex
+	random   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Backoff returns the interval to wait after the given failed attempt, starting from 1.
+func (p Policy) Backoff(attempt int) time.Duration {
+	interval, max, multiplier := p.InitialInterval, p.MaxInterval, p.Multiplier
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	backoff := float64(interval)
+	for i := 1; i < attempt && backoff < float64(max); i++ {
+		backoff *= multiplier
+	}
+	if backoff > float64(max) {
+		backoff = float64(max)
+	}
+
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		randLock.Lock()
+		r := random.Float64()
+		randLock.Unlock()
+		backoff += backoff * jitter * (2*r - 1)
+	}
+	return time.Duration(backoff)
+}
+
+// IsRetryable reports whether err is retryable by the policy.
+func (p Policy) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return true
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that it is never retried, Do returns the wrapped error.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, the policy
+// gives up or ctx is done. The last error of fn is returned.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if !p.IsRetryable(err) {
+			return unwrapPermanent(err)
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return err
+		}
+
+		backoff := p.Backoff(attempt)
+		if p.MaxElapsedTime > 0 && time.Since(start)+backoff > p.MaxElapsedTime {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func unwrapPermanent(err error) error {
+	if permanent, ok := err.(*permanentError); ok {
+		return permanent.err
+	}
+	return err
+}
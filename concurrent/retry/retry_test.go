@@ -0,0 +1,95 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTemporary = errors.New("temporary error")
+
+func TestDo(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), Constant(time.Millisecond, 5), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTemporary
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = Do(context.Background(), Constant(time.Millisecond, 5), func(ctx context.Context) error {
+		calls++
+		return errTemporary
+	})
+	assert.Equal(t, errTemporary, err)
+	assert.Equal(t, 5, calls)
+}
+
+func TestDoPermanent(t *testing.T) {
+	fatal := errors.New("fatal")
+
+	var calls int
+	err := Do(context.Background(), Policy{}, func(ctx context.Context) error {
+		calls++
+		return Permanent(fatal)
+	})
+	assert.Equal(t, fatal, err)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	p := Policy{
+		InitialInterval: time.Millisecond,
+		Retryable: func(err error) bool {
+			return err == errTemporary
+		},
+	}
+	err = Do(context.Background(), p, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errTemporary
+		}
+		return fatal
+	})
+	assert.Equal(t, fatal, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestDoContextAndElapsed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := Do(ctx, Constant(5*time.Millisecond, 0), func(ctx context.Context) error {
+		return errTemporary
+	})
+	assert.Equal(t, errTemporary, err)
+	assert.NotNil(t, ctx.Err())
+
+	start := time.Now()
+	p := Policy{InitialInterval: 5 * time.Millisecond, MaxElapsedTime: 30 * time.Millisecond}
+	err = Do(context.Background(), p, func(ctx context.Context) error {
+		return errTemporary
+	})
+	assert.Equal(t, errTemporary, err)
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{InitialInterval: time.Second, MaxInterval: 5 * time.Second, Multiplier: 2}
+	assert.Equal(t, time.Second, p.Backoff(1))
+	assert.Equal(t, 2*time.Second, p.Backoff(2))
+	assert.Equal(t, 4*time.Second, p.Backoff(3))
+	assert.Equal(t, 5*time.Second, p.Backoff(10))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		b := p.Backoff(1)
+		assert.True(t, b >= 500*time.Millisecond && b <= 1500*time.Millisecond)
+	}
+}
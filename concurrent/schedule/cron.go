@@ -0,0 +1,303 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the next activation time after t, the zero time means
+// that there is no activation any more.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	seconds = bounds{0, 59, nil}
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	dom     = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dow = bounds{0, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// CronSchedule is a schedule of a cron expression, the times are matched
+// in the location of the time passed to Next.
+type CronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	domStar, dowStar                      bool
+}
+
+// EverySchedule activates at a fixed interval.
+type EverySchedule struct {
+	Interval time.Duration
+}
+
+// Every returns a schedule of a fixed interval rounded up to a second.
+func Every(interval time.Duration) EverySchedule {
+	if interval < time.Second {
+		interval = time.Second
+	}
+	if rem := interval % time.Second; rem > 0 {
+		interval += time.Second - rem
+	}
+	return EverySchedule{Interval: interval}
+}
+
+func (s EverySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Second).Add(s.Interval)
+}
+
+// Parse parses a cron expression of 5 fields (minute hour day-of-month month
+// day-of-week) or 6 fields with a leading second field. Fields accept *, ?,
+// lists (1,2), ranges (1-5), steps (*/15, 1-30/5) and the names of months
+// and weekdays. The descriptors @yearly, @annually, @monthly, @weekly, @daily,
+// @midnight, @hourly and @every <duration> are supported as well.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval of %q: %w", spec, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("interval of %q must be positive", spec)
+		}
+		return Every(d), nil
+	}
+	if strings.HasPrefix(spec, "@") {
+		expr, ok := descriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("unknown descriptor %q", spec)
+		}
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("expected 5 or 6 fields in %q, found %d", spec, len(fields))
+	}
+
+	s := &CronSchedule{}
+	var err error
+	if s.second, _, err = parseField(fields[0], seconds); err != nil {
+		return nil, err
+	}
+	if s.minute, _, err = parseField(fields[1], minutes); err != nil {
+		return nil, err
+	}
+	if s.hour, _, err = parseField(fields[2], hours); err != nil {
+		return nil, err
+	}
+	if s.dom, s.domStar, err = parseField(fields[3], dom); err != nil {
+		return nil, err
+	}
+	if s.month, _, err = parseField(fields[4], months); err != nil {
+		return nil, err
+	}
+	if s.dow, s.dowStar, err = parseField(fields[5], dow); err != nil {
+		return nil, err
+	}
+	// 7 is sunday as well
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+		s.dow &^= 1 << 7
+	}
+	return s, nil
+}
+
+// MustParse is like Parse but panics if the spec can not be parsed.
+func MustParse(spec string) Schedule {
+	s, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseField returns the bits of the values of field,
+// star reports whether the field is * or ?.
+func parseField(field string, b bounds) (bits uint64, star bool, err error) {
+	for _, part := range strings.Split(field, ",") {
+		partBits, partStar, err := parseRange(part, b)
+		if err != nil {
+			return 0, false, err
+		}
+		bits |= partBits
+		star = star || partStar
+	}
+	return bits, star, nil
+}
+
+func parseRange(expr string, b bounds) (uint64, bool, error) {
+	rangeAndStep := strings.Split(expr, "/")
+	if len(rangeAndStep) > 2 {
+		return 0, false, fmt.Errorf("too many slashes in %q", expr)
+	}
+
+	var start, end uint
+	var star bool
+	lowAndHigh := strings.Split(rangeAndStep[0], "-")
+	switch {
+	case lowAndHigh[0] == "*" || lowAndHigh[0] == "?":
+		if len(lowAndHigh) > 1 {
+			return 0, false, fmt.Errorf("invalid range %q", expr)
+		}
+		start, end = b.min, b.max
+		star = len(rangeAndStep) == 1
+	case len(lowAndHigh) <= 2:
+		var err error
+		if start, err = parseValue(lowAndHigh[0], b); err != nil {
+			return 0, false, err
+		}
+		end = start
+		if len(lowAndHigh) == 2 {
+			if end, err = parseValue(lowAndHigh[1], b); err != nil {
+				return 0, false, err
+			}
+		}
+	default:
+		return 0, false, fmt.Errorf("too many hyphens in %q", expr)
+	}
+
+	step := uint(1)
+	if len(rangeAndStep) == 2 {
+		n, err := strconv.ParseUint(rangeAndStep[1], 10, 32)
+		if err != nil || n == 0 {
+			return 0, false, fmt.Errorf("invalid step in %q", expr)
+		}
+		step = uint(n)
+		// N/step means N-max/step
+		if len(lowAndHigh) == 1 && !strings.ContainsAny(lowAndHigh[0], "*?") {
+			end = b.max
+		}
+	}
+
+	if start < b.min || end > b.max || start > end {
+		return 0, false, fmt.Errorf("%q is out of range [%d, %d]", expr, b.min, b.max)
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+	return bits, star, nil
+}
+
+func parseValue(s string, b bounds) (uint, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return uint(n), nil
+}
+
+// Next returns the first time after t matching the expression, or the zero
+// time if there is none within five years.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+	added := false
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for 1<<uint(t.Month())&s.month == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.hour == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.minute == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Second())&s.second == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t
+}
+
+// dayMatches matches either day-of-month or day-of-week when both are
+// restricted, as cron does.
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := 1<<uint(t.Day())&s.dom != 0
+	dowMatch := 1<<uint(t.Weekday())&s.dow != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,192 @@
+package schedule
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	base := time.Date(2026, 10, 18, 10, 30, 15, 0, time.UTC) // Sunday
+
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 18, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * * *", time.Date(2026, 10, 18, 10, 30, 30, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * mon-fri", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2026, 10, 19, 10, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2026, 10, 18, 10, 31, 45, 0, time.UTC)},
+		{"@every 1200ms", time.Date(2026, 10, 18, 10, 30, 17, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, c := range cases {
+		s, err := Parse(c.spec)
+		assert.Nil(t, err, c.spec)
+		assert.Equal(t, c.next, s.Next(base), c.spec)
+	}
+
+	for _, spec := range []string{"", "* * *", "60 * * * *", "* * * * * * *", "*/0 * * * *", "5-1 * * * *", "@often", "@every -1s"} {
+		_, err := Parse(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+// stepSchedule activates at the given times only.
+type stepSchedule struct {
+	times []time.Time
+}
+
+func (s *stepSchedule) Next(t time.Time) time.Time {
+	for _, next := range s.times {
+		if next.After(t) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+func newStepSchedule(offsets ...time.Duration) *stepSchedule {
+	now := time.Now()
+	s := &stepSchedule{}
+	for _, offset := range offsets {
+		s.times = append(s.times, now.Add(offset))
+	}
+	return s
+}
+
+func TestSchedulerOverlap(t *testing.T) {
+	s, err := New(Config{Workers: 8})
+	assert.Nil(t, err)
+
+	release := make(chan struct{})
+	var skipRuns, queueRuns, concurrentRuns, running, maxRunning int32
+	blocked := func(counter *int32) Job {
+		return func(ctx context.Context) error {
+			atomic.AddInt32(counter, 1)
+			if counter == &concurrentRuns {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+			}
+			<-release
+			return nil
+		}
+	}
+
+	offsets := []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 60 * time.Millisecond}
+	skipID, err := s.Add(JobConfig{Name: "skip", Schedule: newStepSchedule(offsets...), Overlap: OverlapSkip}, blocked(&skipRuns))
+	assert.Nil(t, err)
+	_, err = s.Add(JobConfig{Name: "queue", Schedule: newStepSchedule(offsets...), Overlap: OverlapQueue}, blocked(&queueRuns))
+	assert.Nil(t, err)
+	_, err = s.Add(JobConfig{Name: "concurrent", Schedule: newStepSchedule(offsets...), Overlap: OverlapConcurrent}, blocked(&concurrentRuns))
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&concurrentRuns) == 3
+	}, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	infos := s.List()
+	assert.Len(t, infos, 3)
+	assert.Equal(t, "skip", infos[0].Name)
+	assert.Equal(t, uint64(2), infos[0].Skipped)
+	assert.Equal(t, 2, infos[1].Queued)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&skipRuns))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&queueRuns))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&maxRunning))
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&queueRuns) == 3
+	}, time.Second, time.Millisecond)
+
+	assert.True(t, s.Cancel(skipID))
+	assert.False(t, s.Cancel(skipID))
+
+	assert.Nil(t, s.Stop(context.Background()))
+	_, err = s.Add(JobConfig{Spec: "@hourly"}, func(ctx context.Context) error { return nil })
+	assert.Equal(t, ErrStopped, err)
+}
+
+func TestSchedulerStop(t *testing.T) {
+	s, err := New(Config{Workers: 1, QueueSize: 1})
+	assert.Nil(t, err)
+
+	// the first run holds the worker, the second one is queued and the
+	// third one waits for the queue
+	var runs int32
+	release := make(chan struct{})
+	offsets := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}
+	_, err = s.Add(JobConfig{Schedule: newStepSchedule(offsets...), Overlap: OverlapConcurrent}, func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return ctx.Err()
+	})
+	assert.Nil(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	assert.Nil(t, s.Stop(ctx))
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+	assert.Equal(t, ErrStopped, s.Stop(ctx))
+}
+
+func TestSchedulerStopTimeout(t *testing.T) {
+	s, err := New(Config{Workers: 1})
+	assert.Nil(t, err)
+
+	// the job ignores its ctx
+	release := make(chan struct{})
+	defer close(release)
+	_, err = s.Add(JobConfig{Schedule: newStepSchedule(10 * time.Millisecond)}, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	assert.Nil(t, err)
+	time.Sleep(30 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.NotNil(t, s.Stop(ctx))
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
+
+func TestActivations(t *testing.T) {
+	base := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	every := Every(time.Minute)
+
+	runs, following := activations(every, base, base.Add(10*time.Second), 3)
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, base.Add(time.Minute), following)
+
+	// 5 activations were missed but only 3 of them are caught up
+	runs, following = activations(every, base, base.Add(5*time.Minute+10*time.Second), 3)
+	assert.Equal(t, 4, runs)
+	assert.Equal(t, base.Add(6*time.Minute), following)
+
+	runs, _ = activations(every, base, base.Add(5*time.Minute+10*time.Second), 0)
+	assert.Equal(t, 1, runs)
+}
@@ -0,0 +1,346 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shima-park/tools/concurrent/pool"
+)
+
+var ErrStopped = errors.New("scheduler is stopped")
+
+type Job func(ctx context.Context) error
+
+type OverlapPolicy int
+
+const (
+	// OverlapSkip skips an activation while the previous run is still running.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue runs an activation after the previous run finished.
+	OverlapQueue
+	// OverlapConcurrent runs every activation immediately.
+	OverlapConcurrent
+)
+
+type JobConfig struct {
+	Name     string
+	Spec     string   // cron expression or descriptor, see Parse
+	Schedule Schedule // used instead of Spec if it is not nil
+	Overlap  OverlapPolicy
+	// Jitter delays every activation by a random duration in [0, Jitter).
+	Jitter time.Duration
+	// CatchUp is the maximum number of missed activations which are run
+	// when the scheduler is late, 0 skips the missed activations.
+	CatchUp int
+}
+
+type JobInfo struct {
+	ID      uint64
+	Name    string
+	Spec    string
+	Prev    time.Time // start of the last run
+	Next    time.Time // next activation, zero if the schedule has no activation any more
+	Running int
+	Queued  int
+	Runs    uint64
+	Skipped uint64
+	LastErr error
+}
+
+type Config struct {
+	Workers   int            // number of workers running the jobs, default 1
+	QueueSize int            // size of the task queue of the pool, default Workers
+	Location  *time.Location // location the cron expressions are matched in, default time.Local
+	ErrHandle func(name string, err error)
+}
+
+type job struct {
+	id       uint64
+	config   JobConfig
+	schedule Schedule
+	fn       Job
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	lock sync.Mutex
+	info JobInfo
+}
+
+// Scheduler runs jobs on the workers of an AdjustablePool.
+type Scheduler struct {
+	config Config
+	pool   *pool.AdjustablePool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	lock   sync.Mutex
+	nextID uint64
+	jobs   map[uint64]*job
+}
+
+func New(config Config) (*Scheduler, error) {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = config.Workers
+	}
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+
+	p, err := pool.NewTaskPool(config.QueueSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Add(config.Workers, nil); err != nil {
+		p.Stop()
+		return nil, err
+	}
+
+	s := &Scheduler{
+		config: config,
+		pool:   p,
+		jobs:   make(map[uint64]*job),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s, nil
+}
+
+// Pool returns the pool running the jobs.
+func (s *Scheduler) Pool() *pool.AdjustablePool {
+	return s.pool
+}
+
+// Add schedules the job and returns its ID.
+func (s *Scheduler) Add(config JobConfig, fn Job) (uint64, error) {
+	if fn == nil {
+		return 0, errors.New("must provide function for job")
+	}
+
+	schedule := config.Schedule
+	if schedule == nil {
+		var err error
+		if schedule, err = Parse(config.Spec); err != nil {
+			return 0, err
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.ctx.Err() != nil {
+		return 0, ErrStopped
+	}
+
+	s.nextID++
+	j := &job{
+		id:       s.nextID,
+		config:   config,
+		schedule: schedule,
+		fn:       fn,
+	}
+	j.ctx, j.cancel = context.WithCancel(context.Background())
+	j.info = JobInfo{ID: j.id, Name: config.Name, Spec: config.Spec}
+	s.jobs[j.id] = j
+
+	s.wg.Add(1)
+	go s.run(j)
+
+	return j.id, nil
+}
+
+// Cancel unschedules the job and cancels its running runs.
+func (s *Scheduler) Cancel(id uint64) bool {
+	s.lock.Lock()
+	j, ok := s.jobs[id]
+	delete(s.jobs, id)
+	s.lock.Unlock()
+
+	if ok {
+		j.cancel()
+	}
+	return ok
+}
+
+// List returns the scheduled jobs ordered by ID.
+func (s *Scheduler) List() []JobInfo {
+	s.lock.Lock()
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		j.lock.Lock()
+		infos = append(infos, j.info)
+		j.lock.Unlock()
+	}
+	s.lock.Unlock()
+
+	sort.Slice(infos, func(i, k int) bool {
+		return infos[i].ID < infos[k].ID
+	})
+	return infos
+}
+
+// Stop stops scheduling, cancels the running jobs and waits until ctx is
+// done for them to return, see AdjustablePool.Shutdown.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	s.lock.Lock()
+	if s.ctx.Err() != nil {
+		s.lock.Unlock()
+		return ErrStopped
+	}
+	s.cancel()
+	for _, j := range s.jobs {
+		j.cancel()
+	}
+	s.lock.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(stopped)
+	}()
+
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if serr := s.pool.Shutdown(ctx); err == nil {
+		err = serr
+	}
+	return err
+}
+
+func (s *Scheduler) run(j *job) {
+	defer s.wg.Done()
+
+	next := j.schedule.Next(time.Now().In(s.config.Location))
+	for {
+		j.lock.Lock()
+		j.info.Next = next
+		j.lock.Unlock()
+
+		// the job stays listed until it is canceled
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next) + jitter(j.config.Jitter))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-j.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		runs, following := activations(j.schedule, next, time.Now().In(s.config.Location), j.config.CatchUp)
+		for i := 0; i < runs; i++ {
+			s.activate(j)
+		}
+		next = following
+	}
+}
+
+// activations returns the number of runs for the activation at next, which
+// includes up to catchUp activations missed until now, and the following
+// activation after now.
+func activations(schedule Schedule, next, now time.Time, catchUp int) (int, time.Time) {
+	following := schedule.Next(next)
+	missed := 0
+	for !following.IsZero() && !following.After(now) {
+		missed++
+		following = schedule.Next(following)
+	}
+
+	if missed > catchUp {
+		missed = catchUp
+	}
+	return 1 + missed, following
+}
+
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+func (s *Scheduler) activate(j *job) {
+	j.lock.Lock()
+	if j.info.Running > 0 {
+		switch j.config.Overlap {
+		case OverlapSkip:
+			j.info.Skipped++
+			j.lock.Unlock()
+			return
+		case OverlapQueue:
+			j.info.Queued++
+			j.lock.Unlock()
+			return
+		}
+	}
+	j.info.Running++
+	j.lock.Unlock()
+
+	s.submit(j)
+}
+
+func (s *Scheduler) submit(j *job) {
+	var ran bool
+	f, err := s.pool.Submit(j.ctx, func(ctx context.Context) error {
+		ran = true
+		j.lock.Lock()
+		j.info.Prev = time.Now()
+		j.lock.Unlock()
+
+		return j.fn(ctx)
+	})
+	if err != nil {
+		s.finish(j, false, err)
+		return
+	}
+
+	go func() {
+		err := f.Wait(context.Background())
+		s.finish(j, ran, err)
+	}()
+}
+
+// finish records the result of a run and submits the queued activation.
+func (s *Scheduler) finish(j *job, ran bool, err error) {
+	if ran && err != nil && s.config.ErrHandle != nil {
+		s.config.ErrHandle(j.config.Name, err)
+	}
+
+	j.lock.Lock()
+	if ran {
+		j.info.Runs++
+		j.info.LastErr = err
+	}
+	j.info.Running--
+	queued := j.info.Queued > 0 && j.ctx.Err() == nil && s.ctx.Err() == nil
+	if queued {
+		j.info.Queued--
+		j.info.Running++
+	}
+	j.lock.Unlock()
+
+	if queued {
+		s.submit(j)
+	}
+}
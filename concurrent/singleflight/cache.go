@@ -0,0 +1,167 @@
+package singleflight
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type CacheConfig struct {
+	// Size is the maximum number of cached results, the least recently used
+	// ones are evicted. 0 disables caching and only coalesces the calls.
+	Size int
+	// TTL of the positive results, 0 means they never expire.
+	TTL time.Duration
+	// NegativeTTL of the negative results, 0 disables negative caching.
+	NegativeTTL time.Duration
+	// IsNegative classifies a result, default a result with error is negative.
+	// Errors which are not negative are never cached.
+	IsNegative func(v interface{}, err error) bool
+}
+
+type entry struct {
+	key      string
+	val      interface{}
+	err      error
+	expireAt time.Time
+}
+
+// Cache coalesces the concurrent loads of a key and keeps their results in
+// a bounded LRU with TTL.
+type Cache struct {
+	config CacheConfig
+	group  Group
+
+	lock  sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+func NewCache(config CacheConfig) *Cache {
+	if config.Size < 0 {
+		config.Size = 0
+	}
+	if config.IsNegative == nil {
+		config.IsNegative = func(v interface{}, err error) bool {
+			return err != nil
+		}
+	}
+
+	return &Cache{
+		config: config,
+		ll:     list.New(),
+		items:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached result of key or loads it, the concurrent loads of
+// the same key are executed once.
+func (c *Cache) Get(key string, load func() (interface{}, error)) (interface{}, error) {
+	if e, ok := c.lookup(key); ok {
+		return e.val, e.err
+	}
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		// the result may have been stored by the previous call
+		if e, ok := c.lookup(key); ok {
+			return e.val, e.err
+		}
+
+		v, err := load()
+		c.store(key, v, err)
+		return v, err
+	})
+	return v, err
+}
+
+func (c *Cache) lookup(key string) (*entry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := elem.Value.(*entry)
+	if !e.expireAt.IsZero() && !time.Now().Before(e.expireAt) {
+		c.removeElement(elem)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(elem)
+	return e, true
+}
+
+func (c *Cache) store(key string, v interface{}, err error) {
+	if c.config.Size == 0 {
+		return
+	}
+
+	ttl := c.config.TTL
+	if c.config.IsNegative(v, err) {
+		if c.config.NegativeTTL <= 0 {
+			return
+		}
+		ttl = c.config.NegativeTTL
+	} else if err != nil {
+		return
+	}
+
+	e := &entry{key: key, val: v, err: err}
+	if ttl > 0 {
+		e.expireAt = time.Now().Add(ttl)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value = e
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(e)
+	for c.ll.Len() > c.config.Size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *Cache) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*entry).key)
+}
+
+// Peek returns the cached result of key without loading it,
+// ok is false if key is not cached or has expired.
+func (c *Cache) Peek(key string) (v interface{}, err error, ok bool) {
+	e, ok := c.lookup(key)
+	if !ok {
+		return nil, nil, false
+	}
+	return e.val, e.err, true
+}
+
+// Set stores v as the result of key as if it was loaded.
+func (c *Cache) Set(key string, v interface{}) {
+	c.store(key, v, nil)
+}
+
+// Invalidate removes the cached result of key.
+func (c *Cache) Invalidate(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// Len returns the number of cached results including the expired ones
+// which have not been evicted yet.
+func (c *Cache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.ll.Len()
+}
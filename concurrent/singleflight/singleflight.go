@@ -0,0 +1,100 @@
+package singleflight
+
+import (
+	"fmt"
+	"sync"
+)
+
+type call struct {
+	wg   sync.WaitGroup
+	val  interface{}
+	err  error
+	dups int
+	chs  []chan<- Result
+}
+
+// Result is the result of DoChan.
+type Result struct {
+	Val    interface{}
+	Err    error
+	Shared bool
+}
+
+// Group coalesces the concurrent calls of the same key into one execution.
+type Group struct {
+	lock  sync.Mutex
+	calls map[string]*call
+}
+
+// Do executes fn once for all the concurrent callers of key,
+// shared reports whether the result was given to more than one caller.
+func (g *Group) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.lock.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.lock.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.lock.Unlock()
+
+	g.doCall(c, key, fn)
+	return c.val, c.err, c.dups > 0
+}
+
+// DoChan is like Do but returns a channel that receives the result.
+func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
+
+	g.lock.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		c.chs = append(c.chs, ch)
+		g.lock.Unlock()
+		return ch
+	}
+	c := &call{chs: []chan<- Result{ch}}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.lock.Unlock()
+
+	go g.doCall(c, key, fn)
+	return ch
+}
+
+func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("singleflight: call of key %q panicked: %v", key, r)
+		}
+
+		g.lock.Lock()
+		c.wg.Done()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		for _, ch := range c.chs {
+			ch <- Result{Val: c.val, Err: c.err, Shared: c.dups > 0}
+		}
+		g.lock.Unlock()
+	}()
+
+	c.val, c.err = fn()
+}
+
+// Forget makes the following calls of key execute fn again
+// instead of waiting for the in-flight call.
+func (g *Group) Forget(key string) {
+	g.lock.Lock()
+	delete(g.calls, key)
+	g.lock.Unlock()
+}
@@ -0,0 +1,102 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupDo(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	var shared int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, s := g.Do("row", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "value", nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, "value", v)
+			if s {
+				atomic.AddInt32(&shared, 1)
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, int32(10), atomic.LoadInt32(&shared))
+
+	r := <-g.DoChan("row", func() (interface{}, error) {
+		panic("boom")
+	})
+	assert.NotNil(t, r.Err)
+	assert.False(t, r.Shared)
+}
+
+func TestCache(t *testing.T) {
+	notFound := errors.New("not found")
+	c := NewCache(CacheConfig{
+		Size:        2,
+		TTL:         time.Hour,
+		NegativeTTL: 20 * time.Millisecond,
+	})
+
+	var loads int32
+	load := func(v interface{}, err error) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			atomic.AddInt32(&loads, 1)
+			return v, err
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		v, err := c.Get("a", load(1, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, v)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	for i := 0; i < 3; i++ {
+		_, err := c.Get("missing", load(nil, notFound))
+		assert.Equal(t, notFound, err)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+
+	time.Sleep(30 * time.Millisecond)
+	_, err := c.Get("missing", load(nil, notFound))
+	assert.Equal(t, notFound, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&loads))
+
+	// "a" is the least recently used and evicted
+	_, _ = c.Get("b", load(2, nil))
+	assert.Equal(t, 2, c.Len())
+	v, _ := c.Get("a", load(10, nil))
+	assert.Equal(t, 10, v)
+
+	c.Invalidate("a")
+	v, _ = c.Get("a", load(11, nil))
+	assert.Equal(t, 11, v)
+
+	c.Set("c", 3)
+	v, err, ok := c.Peek("c")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, v)
+	_, _, ok = c.Peek("b")
+	assert.False(t, ok)
+}
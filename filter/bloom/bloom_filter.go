@@ -2,17 +2,20 @@ package bloom
 
 import (
 	"context"
+	"fmt"
+
 	"github.com/go-redis/redis/v8"
 )
 
 // BloomFilter doc: https://oss.redislabs.com/redisbloom/Bloom_Commands/
+// The client may be a single node, sentinel or cluster client.
 type BloomFilter struct {
-	client *redis.Client
+	processor
 }
 
-func NewBloomFilter(client *redis.Client) *BloomFilter {
+func NewBloomFilter(client redis.UniversalClient) *BloomFilter {
 	return &BloomFilter{
-		client: client,
+		processor: newProcessor(client),
 	}
 }
 
@@ -33,7 +36,7 @@ capacity大小不能大于4294967293，目前作者的实现超过此值会报
 */
 func (f *BloomFilter) Reserve(ctx context.Context, key string, errorRate float64, capacity int64) error {
 	cmd := redis.NewStatusCmd(ctx, "BF.RESERVE", key, errorRate, capacity)
-	f.client.Process(ctx, cmd)
+	f.process(ctx, cmd)
 	return cmd.Err()
 }
 
@@ -42,7 +45,7 @@ func (f *BloomFilter) Reserve(ctx context.Context, key string, errorRate float64
 // item : The item to add
 func (f *BloomFilter) Add(ctx context.Context, key, item string) (bool, error) {
 	cmd := redis.NewBoolCmd(ctx, "BF.ADD", key, item)
-	f.client.Process(ctx, cmd)
+	f.process(ctx, cmd)
 	return cmd.Result()
 }
 
@@ -55,7 +58,7 @@ func (f *BloomFilter) MAdd(ctx context.Context, key string, items ...string) ([]
 		args = append(args, item)
 	}
 	cmd := redis.NewBoolSliceCmd(ctx, args...)
-	f.client.Process(ctx, cmd)
+	f.process(ctx, cmd)
 	return cmd.Result()
 }
 
@@ -64,19 +67,208 @@ func (f *BloomFilter) MAdd(ctx context.Context, key string, items ...string) ([]
 // item : the item to check for
 func (f *BloomFilter) Exists(ctx context.Context, key, item string) (bool, error) {
 	cmd := redis.NewBoolCmd(ctx, "BF.EXISTS", key, item)
-	f.client.Process(ctx, cmd)
+	f.process(ctx, cmd)
 	return cmd.Result()
 }
 
-// Determines if one or more items may exist in the filter or not.
+// MExists Determines if one or more items may exist in the filter or not.
 // key : name of the filter
 // items : one or more items to check
-func (f *BloomFilter) MEXISTS(ctx context.Context, key string, items ...string) ([]bool, error) {
+func (f *BloomFilter) MExists(ctx context.Context, key string, items ...string) ([]bool, error) {
 	args := []interface{}{"BF.MEXISTS", key}
 	for _, item := range items {
 		args = append(args, item)
 	}
 	cmd := redis.NewBoolSliceCmd(ctx, args...)
-	f.client.Process(ctx, cmd)
+	f.process(ctx, cmd)
+	return cmd.Result()
+}
+
+// MEXISTS is the same as MExists.
+//
+// Deprecated: use MExists instead.
+func (f *BloomFilter) MEXISTS(ctx context.Context, key string, items ...string) ([]bool, error) {
+	return f.MExists(ctx, key, items...)
+}
+
+// InsertOptions are the optional arguments of BF.INSERT, zero values are omitted.
+type InsertOptions struct {
+	// Capacity of the filter if it is created, it is ignored if the filter already exists.
+	Capacity int64
+	// ErrorRate of the filter if it is created, it is ignored if the filter already exists.
+	ErrorRate float64
+	// Expansion is the multiple of the capacity of the sub-filter created when the filter is full.
+	Expansion int64
+	// NoCreate does not create the filter if it does not exist, an error is returned instead.
+	NoCreate bool
+	// NonScaling returns an error instead of creating a sub-filter when the filter is full.
+	NonScaling bool
+}
+
+// Insert Adds one or more items to the Bloom Filter, creating the filter if it does not yet exist.
+// key : The name of the filter
+// opts : Optional arguments, may be nil
+// items : One or more items to add
+func (f *BloomFilter) Insert(ctx context.Context, key string, opts *InsertOptions, items ...string) ([]bool, error) {
+	args := []interface{}{"BF.INSERT", key}
+	if opts != nil {
+		if opts.Capacity > 0 {
+			args = append(args, "CAPACITY", opts.Capacity)
+		}
+		if opts.ErrorRate > 0 {
+			args = append(args, "ERROR", opts.ErrorRate)
+		}
+		if opts.Expansion > 0 {
+			args = append(args, "EXPANSION", opts.Expansion)
+		}
+		if opts.NoCreate {
+			args = append(args, "NOCREATE")
+		}
+		if opts.NonScaling {
+			args = append(args, "NONSCALING")
+		}
+	}
+	args = append(args, "ITEMS")
+	for _, item := range items {
+		args = append(args, item)
+	}
+	cmd := redis.NewBoolSliceCmd(ctx, args...)
+	f.process(ctx, cmd)
 	return cmd.Result()
 }
+
+// Info is the result of BF.INFO.
+type Info struct {
+	Capacity  int64 // number of unique items that can be stored before scaling
+	Size      int64 // memory size in bytes
+	Filters   int64 // number of sub-filters
+	Items     int64 // number of items inserted
+	Expansion int64 // expansion rate, 0 if the filter is non-scaling
+}
+
+// Info Returns information about the filter.
+// key : The name of the filter
+func (f *BloomFilter) Info(ctx context.Context, key string) (*Info, error) {
+	cmd := redis.NewSliceCmd(ctx, "BF.INFO", key)
+	f.process(ctx, cmd)
+	vals, err := cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseInfo(vals)
+}
+
+func parseInfo(vals []interface{}) (*Info, error) {
+	if len(vals)%2 != 0 {
+		return nil, fmt.Errorf("bloom: unexpected BF.INFO reply length %d", len(vals))
+	}
+
+	info := &Info{}
+	for i := 0; i < len(vals); i += 2 {
+		name, ok := vals[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("bloom: unexpected BF.INFO field %T", vals[i])
+		}
+		// the expansion rate is nil for non-scaling filters
+		n, _ := vals[i+1].(int64)
+		switch name {
+		case "Capacity":
+			info.Capacity = n
+		case "Size":
+			info.Size = n
+		case "Number of filters":
+			info.Filters = n
+		case "Number of items inserted":
+			info.Items = n
+		case "Expansion rate":
+			info.Expansion = n
+		}
+	}
+	return info, nil
+}
+
+// Card Returns the cardinality of the filter, the number of items that were added and detected as unique.
+// Returns 0 if the filter does not exist.
+// key : The name of the filter
+func (f *BloomFilter) Card(ctx context.Context, key string) (int64, error) {
+	cmd := redis.NewIntCmd(ctx, "BF.CARD", key)
+	f.process(ctx, cmd)
+	return cmd.Result()
+}
+
+// ScanDump Begins an incremental save of the filter, iter is 0 for the first call
+// and the returned iterator must be passed to the next call. The dump is
+// complete when the returned iterator is 0.
+// key : The name of the filter
+// iter : The iterator value returned by the previous call
+func (f *BloomFilter) ScanDump(ctx context.Context, key string, iter int64) (int64, []byte, error) {
+	cmd := redis.NewSliceCmd(ctx, "BF.SCANDUMP", key, iter)
+	f.process(ctx, cmd)
+	vals, err := cmd.Result()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(vals) != 2 {
+		return 0, nil, fmt.Errorf("bloom: unexpected BF.SCANDUMP reply length %d", len(vals))
+	}
+
+	next, ok := vals[0].(int64)
+	if !ok {
+		return 0, nil, fmt.Errorf("bloom: unexpected BF.SCANDUMP iterator %T", vals[0])
+	}
+	var data []byte
+	switch v := vals[1].(type) {
+	case string:
+		data = []byte(v)
+	case nil:
+	default:
+		return 0, nil, fmt.Errorf("bloom: unexpected BF.SCANDUMP data %T", vals[1])
+	}
+	return next, data, nil
+}
+
+// LoadChunk Restores a filter previously saved using ScanDump.
+// key : The name of the filter
+// iter : The iterator value returned by ScanDump along with data
+// data : The data returned by ScanDump
+func (f *BloomFilter) LoadChunk(ctx context.Context, key string, iter int64, data []byte) error {
+	cmd := redis.NewStatusCmd(ctx, "BF.LOADCHUNK", key, iter, data)
+	f.process(ctx, cmd)
+	return cmd.Err()
+}
+
+// Batch is the items of a key for the commands of multiple keys,
+// Results and Err are set by the command.
+type Batch struct {
+	Key     string
+	Items   []string
+	Results []bool
+	Err     error
+}
+
+// MAddMulti Adds the items of every batch to the filter of its key in one pipeline,
+// or in a pipeline per slot for a cluster client.
+// Returns the first error of the batches, the error of each batch is set to its Err.
+func (f *BloomFilter) MAddMulti(ctx context.Context, batches []*Batch) error {
+	return f.processBatches(ctx, "BF.MADD", batches)
+}
+
+// MExistsMulti Checks the items of every batch in the filter of its key in one pipeline,
+// or in a pipeline per slot for a cluster client.
+// Returns the first error of the batches, the error of each batch is set to its Err.
+func (f *BloomFilter) MExistsMulti(ctx context.Context, batches []*Batch) error {
+	return f.processBatches(ctx, "BF.MEXISTS", batches)
+}
+
+func (f *BloomFilter) processBatches(ctx context.Context, name string, batches []*Batch) error {
+	cmds := make([]redis.Cmder, len(batches))
+	for i, b := range batches {
+		cmds[i] = newItemsCmd(ctx, name, b.Key, b.Items)
+	}
+
+	err := f.processPipeline(ctx, cmds)
+	for i, b := range batches {
+		b.Results, b.Err = cmds[i].(*redis.BoolSliceCmd).Result()
+	}
+	return err
+}
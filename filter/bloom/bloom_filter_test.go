@@ -0,0 +1,101 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shima-park/tools/filter/internal/redistest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilterInsert(t *testing.T) {
+	s, client := redistest.NewServer(t)
+	s.Reply("BF.INSERT", func(args []string) interface{} { return []interface{}{int64(1), int64(0)} })
+
+	f := NewBloomFilter(client)
+	ctx := context.Background()
+
+	added, err := f.Insert(ctx, "k", nil, "a", "b")
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, false}, added)
+
+	_, err = f.Insert(ctx, "k", &InsertOptions{}, "a")
+	assert.Nil(t, err)
+	_, err = f.Insert(ctx, "k", &InsertOptions{Capacity: 1000, ErrorRate: 0.01, Expansion: 2}, "a")
+	assert.Nil(t, err)
+	_, err = f.Insert(ctx, "k", &InsertOptions{NoCreate: true, NonScaling: true}, "a")
+	assert.Nil(t, err)
+
+	assert.Equal(t, [][]string{
+		{"BF.INSERT", "k", "ITEMS", "a", "b"},
+		{"BF.INSERT", "k", "ITEMS", "a"},
+		{"BF.INSERT", "k", "CAPACITY", "1000", "ERROR", "0.01", "EXPANSION", "2", "ITEMS", "a"},
+		{"BF.INSERT", "k", "NOCREATE", "NONSCALING", "ITEMS", "a"},
+	}, s.Calls())
+}
+
+func TestBloomFilterScanDump(t *testing.T) {
+	s, client := redistest.NewServer(t)
+	replies := map[string]interface{}{
+		"first":  []interface{}{int64(1), "header"},
+		"last":   []interface{}{int64(0), nil},
+		"length": []interface{}{int64(1)},
+		"iter":   []interface{}{"1", "header"},
+		"data":   []interface{}{int64(1), int64(2)},
+		"error":  errors.New("ERR not found"),
+	}
+	s.Reply("BF.SCANDUMP", func(args []string) interface{} { return replies[args[0]] })
+
+	f := NewBloomFilter(client)
+	ctx := context.Background()
+
+	iter, data, err := f.ScanDump(ctx, "first", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), iter)
+	assert.Equal(t, []byte("header"), data)
+
+	iter, data, err = f.ScanDump(ctx, "last", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), iter)
+	assert.Nil(t, data)
+
+	for _, key := range []string{"length", "iter", "data", "error"} {
+		_, _, err = f.ScanDump(ctx, key, 0)
+		assert.NotNil(t, err, key)
+	}
+}
+
+func TestBloomFilterInfo(t *testing.T) {
+	s, client := redistest.NewServer(t)
+	s.Reply("BF.INFO", func(args []string) interface{} {
+		return []interface{}{
+			"Capacity", int64(100),
+			"Size", int64(296),
+			"Number of filters", int64(1),
+			"Number of items inserted", int64(3),
+			"Expansion rate", int64(2),
+		}
+	})
+
+	info, err := NewBloomFilter(client).Info(context.Background(), "k")
+	assert.Nil(t, err)
+	assert.Equal(t, &Info{Capacity: 100, Size: 296, Filters: 1, Items: 3, Expansion: 2}, info)
+}
+
+func TestParseInfo(t *testing.T) {
+	info, err := parseInfo([]interface{}{
+		"Capacity", int64(100),
+		"Size", int64(296),
+		"Number of filters", int64(1),
+		"Number of items inserted", int64(3),
+		"Expansion rate", nil,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &Info{Capacity: 100, Size: 296, Filters: 1, Items: 3}, info)
+
+	_, err = parseInfo([]interface{}{"Capacity"})
+	assert.NotNil(t, err)
+	_, err = parseInfo([]interface{}{int64(1), int64(2)})
+	assert.NotNil(t, err)
+}
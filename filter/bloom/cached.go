@@ -0,0 +1,286 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/shima-park/tools/concurrent/singleflight"
+)
+
+const (
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = 10 * time.Millisecond
+)
+
+var ErrClosed = errors.New("bloom: filter is closed")
+
+type CachedFilterConfig struct {
+	// CacheSize is the maximum number of cached positive results,
+	// 0 disables the cache.
+	CacheSize int
+	// CacheTTL of the positive results, 0 means they never expire.
+	// Positive results only become stale if the key is deleted.
+	CacheTTL time.Duration
+	// BatchSize is the maximum number of items written by a flush,
+	// default DefaultBatchSize.
+	BatchSize int
+	// FlushInterval is the maximum time an item waits to be written,
+	// default DefaultFlushInterval.
+	FlushInterval time.Duration
+}
+
+// MultiAdder is implemented by the filters which can add the items of
+// multiple keys in one round-trip, such as BloomFilter.
+type MultiAdder interface {
+	MAddMulti(ctx context.Context, batches []*Batch) error
+}
+
+type addRequest struct {
+	key   string
+	item  string
+	added bool
+	err   error
+	done  chan struct{}
+}
+
+// CachedFilter is a Filter in front of another one, usually a BloomFilter.
+// The positive results are cached in memory since an item never disappears
+// from a Bloom filter, and the concurrent Add calls are coalesced into
+// MAdd batches written every FlushInterval or once BatchSize items pending.
+type CachedFilter struct {
+	filter Filter
+	config CachedFilterConfig
+	cache  *singleflight.Cache
+
+	lock   sync.RWMutex
+	closed bool
+	adds   chan *addRequest
+	done   chan struct{}
+}
+
+func NewCachedFilter(filter Filter, config CachedFilterConfig) *CachedFilter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+
+	f := &CachedFilter{
+		filter: filter,
+		config: config,
+		cache: singleflight.NewCache(singleflight.CacheConfig{
+			Size: config.CacheSize,
+			TTL:  config.CacheTTL,
+			IsNegative: func(v interface{}, err error) bool {
+				return err != nil || !v.(bool)
+			},
+		}),
+		adds: make(chan *addRequest, config.BatchSize),
+		done: make(chan struct{}),
+	}
+	go f.write()
+	return f
+}
+
+func cacheKey(key, item string) string {
+	return key + "\x00" + item
+}
+
+func (f *CachedFilter) Reserve(ctx context.Context, key string, errorRate float64, capacity int64) error {
+	return f.filter.Reserve(ctx, key, errorRate, capacity)
+}
+
+// Add queues item to be written by the next flush and waits for the result,
+// it returns false without writing if item is cached as existing.
+func (f *CachedFilter) Add(ctx context.Context, key, item string) (bool, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, _, ok := f.cache.Peek(cacheKey(key, item)); ok {
+		return false, nil
+	}
+
+	req := &addRequest{key: key, item: item, done: make(chan struct{})}
+	if err := f.enqueue(ctx, req); err != nil {
+		return false, err
+	}
+
+	select {
+	case <-req.done:
+		return req.added, req.err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func (f *CachedFilter) enqueue(ctx context.Context, req *addRequest) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+
+	select {
+	case f.adds <- req:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// MAdd writes items immediately, they are cached as existing afterwards.
+func (f *CachedFilter) MAdd(ctx context.Context, key string, items ...string) ([]bool, error) {
+	added, err := f.filter.MAdd(ctx, key, items...)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		f.cache.Set(cacheKey(key, item), true)
+	}
+	return added, nil
+}
+
+// Exists checks the cache first, the concurrent checks of the same item
+// are coalesced into one call.
+func (f *CachedFilter) Exists(ctx context.Context, key, item string) (bool, error) {
+	v, err := f.cache.Get(cacheKey(key, item), func() (interface{}, error) {
+		return f.filter.Exists(ctx, key, item)
+	})
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// MExists checks the cache first, only the missed items are checked in one call.
+func (f *CachedFilter) MExists(ctx context.Context, key string, items ...string) ([]bool, error) {
+	exists := make([]bool, len(items))
+	var (
+		missed  []string
+		indexes []int
+	)
+	for i, item := range items {
+		if _, _, ok := f.cache.Peek(cacheKey(key, item)); ok {
+			exists[i] = true
+			continue
+		}
+		missed = append(missed, item)
+		indexes = append(indexes, i)
+	}
+	if len(missed) == 0 {
+		return exists, nil
+	}
+
+	results, err := f.filter.MExists(ctx, key, missed...)
+	if err != nil {
+		return nil, err
+	}
+	for i, ok := range results {
+		if ok {
+			exists[indexes[i]] = true
+			f.cache.Set(cacheKey(key, missed[i]), true)
+		}
+	}
+	return exists, nil
+}
+
+// Close writes the queued items and stops the writer,
+// Add returns ErrClosed afterwards.
+func (f *CachedFilter) Close(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	f.lock.Lock()
+	if !f.closed {
+		f.closed = true
+		close(f.adds)
+	}
+	f.lock.Unlock()
+
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f *CachedFilter) write() {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.config.FlushInterval)
+	defer ticker.Stop()
+
+	var pending []*addRequest
+	for {
+		select {
+		case req, ok := <-f.adds:
+			if !ok {
+				f.flush(pending)
+				return
+			}
+			pending = append(pending, req)
+			if len(pending) < f.config.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(pending) == 0 {
+				continue
+			}
+		}
+		f.flush(pending)
+		pending = nil
+	}
+}
+
+// flush writes the pending items grouped by key, in one round-trip if the
+// filter is a MultiAdder.
+func (f *CachedFilter) flush(pending []*addRequest) {
+	if len(pending) == 0 {
+		return
+	}
+
+	var (
+		batches []*Batch
+		reqs    [][]*addRequest
+		index   = map[string]int{}
+	)
+	for _, req := range pending {
+		i, ok := index[req.key]
+		if !ok {
+			i = len(batches)
+			index[req.key] = i
+			batches = append(batches, &Batch{Key: req.key})
+			reqs = append(reqs, nil)
+		}
+		batches[i].Items = append(batches[i].Items, req.item)
+		reqs[i] = append(reqs[i], req)
+	}
+
+	ctx := context.Background()
+	if m, ok := f.filter.(MultiAdder); ok {
+		_ = m.MAddMulti(ctx, batches)
+	} else {
+		for _, b := range batches {
+			b.Results, b.Err = f.filter.MAdd(ctx, b.Key, b.Items...)
+		}
+	}
+
+	for i, b := range batches {
+		for j, req := range reqs[i] {
+			req.err = b.Err
+			if b.Err == nil {
+				if j < len(b.Results) {
+					req.added = b.Results[j]
+				}
+				f.cache.Set(cacheKey(req.key, req.item), true)
+			}
+			close(req.done)
+		}
+	}
+}
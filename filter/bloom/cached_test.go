@@ -0,0 +1,99 @@
+package bloom
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingFilter struct {
+	*LocalFilter
+	madds  int32
+	exists int32
+}
+
+func (f *countingFilter) MAdd(ctx context.Context, key string, items ...string) ([]bool, error) {
+	atomic.AddInt32(&f.madds, 1)
+	return f.LocalFilter.MAdd(ctx, key, items...)
+}
+
+func (f *countingFilter) Exists(ctx context.Context, key, item string) (bool, error) {
+	atomic.AddInt32(&f.exists, 1)
+	return f.LocalFilter.Exists(ctx, key, item)
+}
+
+func TestCachedFilter(t *testing.T) {
+	local := &countingFilter{LocalFilter: NewLocalFilter()}
+	f := NewCachedFilter(local, CachedFilterConfig{
+		CacheSize:     100,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+	})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			added, err := f.Add(ctx, "k", strconv.Itoa(i))
+			assert.Nil(t, err)
+			assert.True(t, added)
+		}(i)
+	}
+	wg.Wait()
+	// 2 flushes of 10 items
+	assert.Equal(t, int32(2), atomic.LoadInt32(&local.madds))
+
+	// the added items are cached
+	ok, err := f.Exists(ctx, "k", "0")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	added, err := f.Add(ctx, "k", "0")
+	assert.Nil(t, err)
+	assert.False(t, added)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&local.exists))
+
+	// negative results are not cached
+	for i := 0; i < 2; i++ {
+		ok, err = f.Exists(ctx, "k", "missing")
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&local.exists))
+
+	local.LocalFilter.Add(ctx, "k", "x")
+	exists, err := f.MExists(ctx, "k", "1", "x", "y")
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, true, false}, exists)
+	_, _, ok = f.cache.Peek(cacheKey("k", "x"))
+	assert.True(t, ok)
+}
+
+func TestCachedFilterClose(t *testing.T) {
+	f := NewCachedFilter(NewLocalFilter(), CachedFilterConfig{
+		FlushInterval: time.Hour,
+	})
+	ctx := context.Background()
+
+	result := make(chan bool)
+	go func() {
+		added, _ := f.Add(ctx, "k", "a")
+		result <- added
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the pending item is written by Close
+	assert.Nil(t, f.Close(ctx))
+	assert.True(t, <-result)
+
+	_, err := f.Add(ctx, "k", "b")
+	assert.Equal(t, ErrClosed, err)
+	ok, _ := f.Exists(ctx, "k", "a")
+	assert.True(t, ok)
+}
@@ -0,0 +1,209 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// CuckooFilter supports deleting items unlike BloomFilter.
+// doc: https://oss.redislabs.com/redisbloom/Cuckoo_Commands/
+type CuckooFilter struct {
+	processor
+}
+
+func NewCuckooFilter(client redis.UniversalClient) *CuckooFilter {
+	return &CuckooFilter{
+		processor: newProcessor(client),
+	}
+}
+
+// CuckooReserveOptions are the optional arguments of CF.RESERVE, zero values are omitted.
+type CuckooReserveOptions struct {
+	// BucketSize is the number of items in each bucket. A higher bucket size
+	// value improves the fill rate but also causes a higher error rate and
+	// slightly slower performance.
+	BucketSize int64
+	// MaxIterations is the number of attempts to swap items between buckets
+	// before declaring the filter as full and creating an additional filter.
+	MaxIterations int64
+	// Expansion is the multiple of the capacity of the additional filter
+	// created when the filter is full.
+	Expansion int64
+}
+
+// Reserve Creates an empty cuckoo filter with a single sub-filter for the initial amount of capacity for items.
+// key : The key under which the filter is to be found
+// capacity : Estimated capacity for the filter. Capacity is rounded to the next 2^n number.
+// opts : Optional arguments, may be nil
+func (f *CuckooFilter) Reserve(ctx context.Context, key string, capacity int64, opts *CuckooReserveOptions) error {
+	args := []interface{}{"CF.RESERVE", key, capacity}
+	if opts != nil {
+		if opts.BucketSize > 0 {
+			args = append(args, "BUCKETSIZE", opts.BucketSize)
+		}
+		if opts.MaxIterations > 0 {
+			args = append(args, "MAXITERATIONS", opts.MaxIterations)
+		}
+		if opts.Expansion > 0 {
+			args = append(args, "EXPANSION", opts.Expansion)
+		}
+	}
+	cmd := redis.NewStatusCmd(ctx, args...)
+	f.process(ctx, cmd)
+	return cmd.Err()
+}
+
+// Add Adds an item to the cuckoo filter, creating the filter if it does not exist.
+// The same item can be added multiple times, and each addition is counted by Count.
+// key : The name of the filter
+// item : The item to add
+func (f *CuckooFilter) Add(ctx context.Context, key, item string) (bool, error) {
+	cmd := redis.NewBoolCmd(ctx, "CF.ADD", key, item)
+	f.process(ctx, cmd)
+	return cmd.Result()
+}
+
+// AddNX Adds an item to the cuckoo filter only if the item does not yet exist.
+// key : The name of the filter
+// item : The item to add
+// Returns false if the item may already exist in the filter.
+func (f *CuckooFilter) AddNX(ctx context.Context, key, item string) (bool, error) {
+	cmd := redis.NewBoolCmd(ctx, "CF.ADDNX", key, item)
+	f.process(ctx, cmd)
+	return cmd.Result()
+}
+
+// CuckooInsertOptions are the optional arguments of CF.INSERT.
+type CuckooInsertOptions struct {
+	// Capacity of the filter if it is created, the default capacity of the module is used when it is 0.
+	Capacity int64
+	// NoCreate does not create the filter if it does not exist, an error is returned instead.
+	NoCreate bool
+}
+
+// Insert Adds one or more items to the cuckoo filter, creating the filter if it does not exist yet.
+// key : The name of the filter
+// opts : Optional arguments, may be nil
+// items : One or more items to add
+// Returns 1 for each added item and -1 for each item which could not be added because the filter is full.
+func (f *CuckooFilter) Insert(ctx context.Context, key string, opts *CuckooInsertOptions, items ...string) ([]int64, error) {
+	args := []interface{}{"CF.INSERT", key}
+	if opts != nil {
+		if opts.Capacity > 0 {
+			args = append(args, "CAPACITY", opts.Capacity)
+		}
+		if opts.NoCreate {
+			args = append(args, "NOCREATE")
+		}
+	}
+	args = append(args, "ITEMS")
+	for _, item := range items {
+		args = append(args, item)
+	}
+	cmd := redis.NewIntSliceCmd(ctx, args...)
+	f.process(ctx, cmd)
+	return cmd.Result()
+}
+
+// Exists Checks if an item exists in a cuckoo filter.
+// key : The name of the filter
+// item : The item to check for
+func (f *CuckooFilter) Exists(ctx context.Context, key, item string) (bool, error) {
+	cmd := redis.NewBoolCmd(ctx, "CF.EXISTS", key, item)
+	f.process(ctx, cmd)
+	return cmd.Result()
+}
+
+// MExists Checks if one or more items exist in a cuckoo filter.
+// key : The name of the filter
+// items : One or more items to check for
+func (f *CuckooFilter) MExists(ctx context.Context, key string, items ...string) ([]bool, error) {
+	args := []interface{}{"CF.MEXISTS", key}
+	for _, item := range items {
+		args = append(args, item)
+	}
+	cmd := redis.NewBoolSliceCmd(ctx, args...)
+	f.process(ctx, cmd)
+	return cmd.Result()
+}
+
+// Del Deletes an item once from the filter. If the item exists only once, it will be removed from the filter.
+// If the item was added multiple times, it will still be present.
+// Deleting an item which was not added may delete another item that shares its fingerprint.
+// key : The name of the filter
+// item : The item to delete
+// Returns false if the item was not found.
+func (f *CuckooFilter) Del(ctx context.Context, key, item string) (bool, error) {
+	cmd := redis.NewBoolCmd(ctx, "CF.DEL", key, item)
+	f.process(ctx, cmd)
+	return cmd.Result()
+}
+
+// Count Returns the number of times an item may be in the filter.
+// key : The name of the filter
+// item : The item to count
+func (f *CuckooFilter) Count(ctx context.Context, key, item string) (int64, error) {
+	cmd := redis.NewIntCmd(ctx, "CF.COUNT", key, item)
+	f.process(ctx, cmd)
+	return cmd.Result()
+}
+
+// CuckooInfo is the result of CF.INFO.
+type CuckooInfo struct {
+	Size          int64 // memory size in bytes
+	Buckets       int64 // number of buckets
+	Filters       int64 // number of sub-filters
+	Items         int64 // number of items inserted
+	Deleted       int64 // number of items deleted
+	BucketSize    int64
+	Expansion     int64
+	MaxIterations int64
+}
+
+// Info Returns information about the filter.
+// key : The name of the filter
+func (f *CuckooFilter) Info(ctx context.Context, key string) (*CuckooInfo, error) {
+	cmd := redis.NewSliceCmd(ctx, "CF.INFO", key)
+	f.process(ctx, cmd)
+	vals, err := cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseCuckooInfo(vals)
+}
+
+func parseCuckooInfo(vals []interface{}) (*CuckooInfo, error) {
+	if len(vals)%2 != 0 {
+		return nil, fmt.Errorf("bloom: unexpected CF.INFO reply length %d", len(vals))
+	}
+
+	info := &CuckooInfo{}
+	for i := 0; i < len(vals); i += 2 {
+		name, ok := vals[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("bloom: unexpected CF.INFO field %T", vals[i])
+		}
+		n, _ := vals[i+1].(int64)
+		switch name {
+		case "Size":
+			info.Size = n
+		case "Number of buckets":
+			info.Buckets = n
+		case "Number of filters":
+			info.Filters = n
+		case "Number of items inserted":
+			info.Items = n
+		case "Number of items deleted":
+			info.Deleted = n
+		case "Bucket size":
+			info.BucketSize = n
+		case "Expansion rate":
+			info.Expansion = n
+		case "Max iterations":
+			info.MaxIterations = n
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,102 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shima-park/tools/filter/internal/redistest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCuckooFilter(t *testing.T) {
+	s, client := redistest.NewServer(t)
+	s.Reply("CF.RESERVE", func(args []string) interface{} { return redistest.Status("OK") })
+	s.Reply("CF.ADD", func(args []string) interface{} { return int64(1) })
+	s.Reply("CF.ADDNX", func(args []string) interface{} { return int64(0) })
+	s.Reply("CF.INSERT", func(args []string) interface{} { return []interface{}{int64(1), int64(-1)} })
+	s.Reply("CF.EXISTS", func(args []string) interface{} { return int64(1) })
+	s.Reply("CF.MEXISTS", func(args []string) interface{} { return []interface{}{int64(1), int64(0)} })
+	s.Reply("CF.DEL", func(args []string) interface{} { return int64(0) })
+	s.Reply("CF.COUNT", func(args []string) interface{} { return int64(2) })
+	s.Reply("CF.INFO", func(args []string) interface{} {
+		return []interface{}{
+			"Size", int64(1080),
+			"Number of buckets", int64(512),
+			"Number of filters", int64(1),
+			"Number of items inserted", int64(3),
+			"Number of items deleted", int64(1),
+			"Bucket size", int64(2),
+			"Expansion rate", int64(1),
+			"Max iterations", int64(20),
+		}
+	})
+
+	f := NewCuckooFilter(client)
+	ctx := context.Background()
+
+	assert.Nil(t, f.Reserve(ctx, "k", 1000, nil))
+	assert.Nil(t, f.Reserve(ctx, "k", 1000, &CuckooReserveOptions{BucketSize: 2, MaxIterations: 20, Expansion: 1}))
+
+	ok, err := f.Add(ctx, "k", "a")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = f.AddNX(ctx, "k", "a")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	added, err := f.Insert(ctx, "k", &CuckooInsertOptions{Capacity: 100, NoCreate: true}, "b", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, -1}, added)
+
+	ok, err = f.Exists(ctx, "k", "a")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	exists, err := f.MExists(ctx, "k", "a", "z")
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, false}, exists)
+
+	ok, err = f.Del(ctx, "k", "z")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	n, err := f.Count(ctx, "k", "a")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	info, err := f.Info(ctx, "k")
+	assert.Nil(t, err)
+	assert.Equal(t, &CuckooInfo{
+		Size:          1080,
+		Buckets:       512,
+		Filters:       1,
+		Items:         3,
+		Deleted:       1,
+		BucketSize:    2,
+		Expansion:     1,
+		MaxIterations: 20,
+	}, info)
+
+	assert.Equal(t, [][]string{
+		{"CF.RESERVE", "k", "1000"},
+		{"CF.RESERVE", "k", "1000", "BUCKETSIZE", "2", "MAXITERATIONS", "20", "EXPANSION", "1"},
+		{"CF.ADD", "k", "a"},
+		{"CF.ADDNX", "k", "a"},
+		{"CF.INSERT", "k", "CAPACITY", "100", "NOCREATE", "ITEMS", "b", "c"},
+		{"CF.EXISTS", "k", "a"},
+		{"CF.MEXISTS", "k", "a", "z"},
+		{"CF.DEL", "k", "z"},
+		{"CF.COUNT", "k", "a"},
+		{"CF.INFO", "k"},
+	}, s.Calls())
+}
+
+func TestCuckooFilterError(t *testing.T) {
+	s, client := redistest.NewServer(t)
+	s.Reply("CF.ADD", func(args []string) interface{} { return errors.New("ERR Filter is full") })
+
+	_, err := NewCuckooFilter(client).Add(context.Background(), "k", "a")
+	assert.EqualError(t, err, "ERR Filter is full")
+
+	_, err = parseCuckooInfo([]interface{}{"Size"})
+	assert.NotNil(t, err)
+}
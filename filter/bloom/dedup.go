@@ -0,0 +1,258 @@
+package bloom
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type DedupConfig struct {
+	// BatchSize is the maximum number of items checked by a call of the
+	// filter, default DefaultBatchSize.
+	BatchSize int
+	// Interval is the maximum time an item waits for its batch,
+	// default DefaultFlushInterval.
+	Interval time.Duration
+	// ErrHandle receives the errors of the filter, the items of the failed
+	// batch are emitted as unseen.
+	ErrHandle func(error)
+}
+
+func (c *DedupConfig) init() {
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	if c.Interval <= 0 {
+		c.Interval = DefaultFlushInterval
+	}
+}
+
+func (c *DedupConfig) handleErr(err error) {
+	if c.ErrHandle != nil {
+		c.ErrHandle(err)
+	}
+}
+
+// Dedup emits the items of in which are not seen in the filter of key, the
+// item is identified by keyFunc. The items are checked in batches with
+// MExists, emitted in the order of in and marked as seen with MAdd once
+// their batch has been emitted. The delivery is at-least-once: an item is
+// emitted again when its marking fails or ctx is done before it is marked.
+// The returned channel is closed once in is closed or ctx is done.
+func Dedup(ctx context.Context, filter Filter, key string, in <-chan interface{}, keyFunc func(interface{}) string, config DedupConfig) <-chan interface{} {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	config.init()
+
+	out := make(chan interface{}, config.BatchSize)
+	go func() {
+		defer close(out)
+
+		batchItems(ctx, in, config, func(batch []interface{}) bool {
+			items := make([]string, len(batch))
+			for i, v := range batch {
+				items[i] = keyFunc(v)
+			}
+
+			exists, err := filter.MExists(ctx, key, items...)
+			if err != nil {
+				config.handleErr(err)
+			}
+
+			var (
+				emitted []string
+				seen    = make(map[string]struct{}, len(items))
+			)
+			for i, v := range batch {
+				if err == nil {
+					// the duplicates of the batch are not marked yet
+					if _, ok := seen[items[i]]; exists[i] || ok {
+						continue
+					}
+					seen[items[i]] = struct{}{}
+				}
+
+				select {
+				case out <- v:
+					emitted = append(emitted, items[i])
+				case <-ctx.Done():
+					return false
+				}
+			}
+
+			if len(emitted) > 0 {
+				if _, err := filter.MAdd(ctx, key, emitted...); err != nil {
+					config.handleErr(err)
+				}
+			}
+			return true
+		})
+	}()
+	return out
+}
+
+// DedupItem is an item emitted by DedupAck, it must be either acknowledged
+// or rejected once it has been processed.
+type DedupItem struct {
+	Value interface{}
+
+	key      string
+	dedup    *ackDedup
+	doneOnce sync.Once
+}
+
+// Ack marks the item as seen in the filter, with a CachedFilter the
+// acknowledgements are written in batches.
+func (i *DedupItem) Ack(ctx context.Context) error {
+	var err error
+	i.doneOnce.Do(func() {
+		_, err = i.dedup.filter.Add(ctx, i.dedup.key, i.key)
+		i.dedup.release(i.key)
+	})
+	return err
+}
+
+// Nack releases the item without marking it as seen,
+// so the same item is emitted again when it arrives later.
+func (i *DedupItem) Nack() {
+	i.doneOnce.Do(func() {
+		i.dedup.release(i.key)
+	})
+}
+
+type ackDedup struct {
+	filter Filter
+	key    string
+
+	lock     sync.Mutex
+	inflight map[string]struct{}
+}
+
+// acquire returns false if the item is being processed.
+func (d *ackDedup) acquire(item string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if _, ok := d.inflight[item]; ok {
+		return false
+	}
+	d.inflight[item] = struct{}{}
+	return true
+}
+
+func (d *ackDedup) release(item string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.inflight, item)
+}
+
+// DedupAck is like Dedup, but an item is marked as seen only when it is
+// acknowledged. The items are checked in batches with MExists, an item is
+// not emitted again while it is emitted but not acknowledged or rejected.
+func DedupAck(ctx context.Context, filter Filter, key string, in <-chan interface{}, keyFunc func(interface{}) string, config DedupConfig) <-chan *DedupItem {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	config.init()
+
+	d := &ackDedup{
+		filter:   filter,
+		key:      key,
+		inflight: map[string]struct{}{},
+	}
+
+	out := make(chan *DedupItem, config.BatchSize)
+	go func() {
+		defer close(out)
+
+		batchItems(ctx, in, config, func(batch []interface{}) bool {
+			// the items are acquired before they are checked, so an item
+			// acknowledged meanwhile is seen by the check
+			var (
+				values []interface{}
+				items  []string
+			)
+			for _, v := range batch {
+				item := keyFunc(v)
+				if d.acquire(item) {
+					values = append(values, v)
+					items = append(items, item)
+				}
+			}
+			if len(items) == 0 {
+				return true
+			}
+
+			exists, err := filter.MExists(ctx, key, items...)
+			if err != nil {
+				config.handleErr(err)
+			}
+			for i, v := range values {
+				if err == nil && exists[i] {
+					d.release(items[i])
+					continue
+				}
+
+				select {
+				case out <- &DedupItem{Value: v, key: items[i], dedup: d}:
+				case <-ctx.Done():
+					for _, item := range items[i:] {
+						d.release(item)
+					}
+					return false
+				}
+			}
+			return true
+		})
+	}()
+	return out
+}
+
+// batchItems passes the items of in to flush in batches of size or the
+// items arrived within the interval, until in is closed, ctx is done or
+// flush returns false.
+func batchItems(ctx context.Context, in <-chan interface{}, config DedupConfig, flush func([]interface{}) bool) {
+	var (
+		batch   []interface{}
+		timer   *time.Timer
+		timeout <-chan time.Time
+	)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case v, ok := <-in:
+			if !ok {
+				if len(batch) > 0 {
+					flush(batch)
+				}
+				return
+			}
+
+			batch = append(batch, v)
+			if len(batch) == 1 {
+				timer = time.NewTimer(config.Interval)
+				timeout = timer.C
+			}
+			if len(batch) < config.BatchSize {
+				continue
+			}
+		case <-timeout:
+		}
+
+		timer.Stop()
+		timer, timeout = nil, nil
+		if !flush(batch) {
+			return
+		}
+		batch = nil
+	}
+}
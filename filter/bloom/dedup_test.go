@@ -0,0 +1,189 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stringKey(v interface{}) string {
+	return v.(string)
+}
+
+func feed(items ...string) <-chan interface{} {
+	in := make(chan interface{}, len(items))
+	for _, item := range items {
+		in <- item
+	}
+	close(in)
+	return in
+}
+
+func TestDedup(t *testing.T) {
+	ctx := context.Background()
+	f := NewLocalFilter()
+	f.Add(ctx, "k", "b")
+
+	out := Dedup(ctx, f, "k", feed("a", "b", "c", "a", "d", "c", "e"), stringKey, DedupConfig{BatchSize: 2})
+
+	var got []interface{}
+	for v := range out {
+		got = append(got, v)
+	}
+	assert.Equal(t, []interface{}{"a", "c", "d", "e"}, got)
+}
+
+type failingFilter struct {
+	*LocalFilter
+}
+
+func (f failingFilter) MAdd(ctx context.Context, key string, items ...string) ([]bool, error) {
+	return nil, errors.New("unavailable")
+}
+
+func (f failingFilter) MExists(ctx context.Context, key string, items ...string) ([]bool, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestDedupError(t *testing.T) {
+	var errs int
+	out := Dedup(context.Background(), failingFilter{NewLocalFilter()}, "k", feed("a", "a"), stringKey, DedupConfig{
+		ErrHandle: func(err error) { errs++ },
+	})
+
+	var got []interface{}
+	for v := range out {
+		got = append(got, v)
+	}
+	assert.Equal(t, []interface{}{"a", "a"}, got)
+	assert.Equal(t, 2, errs)
+}
+
+func TestDedupMarkAfterEmit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := NewLocalFilter()
+
+	in := make(chan interface{}, 2)
+	out := Dedup(ctx, f, "k", in, stringKey, DedupConfig{BatchSize: 1})
+
+	// "a" fills the output, so "b" is not emitted
+	in <- "a"
+	in <- "b"
+	assert.Eventually(t, func() bool {
+		ok, _ := f.Exists(context.Background(), "k", "a")
+		return ok
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	var got []interface{}
+	for v := range out {
+		got = append(got, v)
+	}
+	assert.Equal(t, []interface{}{"a"}, got)
+	ok, _ := f.Exists(context.Background(), "k", "b")
+	assert.False(t, ok)
+}
+
+func TestDedupInterval(t *testing.T) {
+	in := make(chan interface{})
+	out := Dedup(context.Background(), NewLocalFilter(), "k", in, stringKey, DedupConfig{
+		BatchSize: 100,
+		Interval:  10 * time.Millisecond,
+	})
+
+	in <- "a"
+	select {
+	case v := <-out:
+		assert.Equal(t, "a", v)
+	case <-time.After(time.Second):
+		t.Fatal("batch is not flushed by interval")
+	}
+	close(in)
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+func TestDedupAck(t *testing.T) {
+	ctx := context.Background()
+	f := NewLocalFilter()
+
+	in := make(chan interface{})
+	out := DedupAck(ctx, f, "k", in, stringKey, DedupConfig{BatchSize: 1})
+
+	in <- "a"
+	a := <-out
+	assert.Equal(t, "a", a.Value)
+
+	// "a" is in flight
+	in <- "a"
+	in <- "b"
+	b := <-out
+	assert.Equal(t, "b", b.Value)
+
+	// "b" is rejected and emitted again
+	b.Nack()
+	in <- "b"
+	b = <-out
+	assert.Equal(t, "b", b.Value)
+
+	assert.Nil(t, a.Ack(ctx))
+	ok, _ := f.Exists(ctx, "k", "a")
+	assert.True(t, ok)
+	in <- "a"
+	in <- "c"
+	assert.Equal(t, "c", (<-out).Value)
+
+	close(in)
+	_, open := <-out
+	assert.False(t, open)
+}
+
+// blockingFilter blocks the MExists calls after the first one until proceed is closed.
+type blockingFilter struct {
+	*LocalFilter
+	calls   int32
+	entered chan struct{}
+	proceed chan struct{}
+}
+
+func (f *blockingFilter) MExists(ctx context.Context, key string, items ...string) ([]bool, error) {
+	if atomic.AddInt32(&f.calls, 1) > 1 {
+		close(f.entered)
+		<-f.proceed
+	}
+	return f.LocalFilter.MExists(ctx, key, items...)
+}
+
+func TestDedupAckInterleaved(t *testing.T) {
+	ctx := context.Background()
+	f := &blockingFilter{
+		LocalFilter: NewLocalFilter(),
+		entered:     make(chan struct{}),
+		proceed:     make(chan struct{}),
+	}
+
+	in := make(chan interface{})
+	out := DedupAck(ctx, f, "k", in, stringKey, DedupConfig{BatchSize: 2, Interval: time.Hour})
+
+	in <- "a"
+	in <- "x"
+	a := <-out
+	assert.Equal(t, "x", (<-out).Value)
+
+	// "a" is acknowledged while the batch of its duplicate is being checked
+	in <- "c"
+	in <- "a"
+	<-f.entered
+	assert.Nil(t, a.Ack(ctx))
+	close(f.proceed)
+
+	assert.Equal(t, "c", (<-out).Value)
+	close(in)
+	_, open := <-out
+	assert.False(t, open)
+}
@@ -0,0 +1,97 @@
+package bloom
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// maxChunkSize bounds the chunk length read by Restore, a larger length
+// means the input is not a dump.
+const maxChunkSize = 512 << 20
+
+var errInvalidDump = errors.New("bloom: invalid dump")
+
+// Dump writes the filter of key to w chunk by chunk with BF.SCANDUMP,
+// the written data can be loaded into any redis with Restore.
+// Each chunk is encoded as the big endian iterator (8 bytes),
+// the data length (4 bytes) and the data.
+func (f *BloomFilter) Dump(ctx context.Context, key string, w io.Writer) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var iter int64
+	for {
+		next, data, err := f.ScanDump(ctx, key, iter)
+		if err != nil {
+			return err
+		}
+		if next == 0 {
+			return nil
+		}
+		if err := writeChunk(w, next, data); err != nil {
+			return err
+		}
+		iter = next
+	}
+}
+
+// Restore loads the filter written by Dump from r into key,
+// key must not exist before restoring.
+func (f *BloomFilter) Restore(ctx context.Context, key string, r io.Reader) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		iter, data, err := readChunk(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := f.LoadChunk(ctx, key, iter, data); err != nil {
+			return err
+		}
+	}
+}
+
+func writeChunk(w io.Writer, iter int64, data []byte) error {
+	var header [12]byte
+	binary.BigEndian.PutUint64(header[:8], uint64(iter))
+	binary.BigEndian.PutUint32(header[8:], uint32(len(data)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// readChunk returns io.EOF only if r ends before a chunk begins.
+func readChunk(r io.Reader) (int64, []byte, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, errInvalidDump
+		}
+		return 0, nil, err
+	}
+
+	iter := int64(binary.BigEndian.Uint64(header[:8]))
+	size := binary.BigEndian.Uint32(header[8:])
+	if iter == 0 || size > maxChunkSize {
+		return 0, nil, errInvalidDump
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, nil, errInvalidDump
+		}
+		return 0, nil, err
+	}
+	return iter, data, nil
+}
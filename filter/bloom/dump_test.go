@@ -0,0 +1,101 @@
+package bloom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/shima-park/tools/filter/internal/redistest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunk(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, writeChunk(&buf, 1, []byte("header")))
+	assert.Nil(t, writeChunk(&buf, 9, []byte{}))
+	assert.Nil(t, writeChunk(&buf, 1025, []byte("bits")))
+
+	r := bytes.NewReader(buf.Bytes())
+	for _, want := range []struct {
+		iter int64
+		data string
+	}{{1, "header"}, {9, ""}, {1025, "bits"}} {
+		iter, data, err := readChunk(r)
+		assert.Nil(t, err)
+		assert.Equal(t, want.iter, iter)
+		assert.Equal(t, want.data, string(data))
+	}
+	_, _, err := readChunk(r)
+	assert.Equal(t, io.EOF, err)
+
+	_, _, err = readChunk(bytes.NewReader(buf.Bytes()[:15]))
+	assert.Equal(t, errInvalidDump, err)
+}
+
+func TestDumpRestore(t *testing.T) {
+	s, client := redistest.NewServer(t)
+
+	// the chunks of the filters by key and iterator
+	var lock sync.Mutex
+	chunks := map[string][]string{
+		"src": {"1", "header", "1025", "\x00bits\xff", "0", ""},
+	}
+	s.Reply("BF.SCANDUMP", func(args []string) interface{} {
+		lock.Lock()
+		defer lock.Unlock()
+
+		dump := chunks[args[0]]
+		for i := 0; i < len(dump); i += 2 {
+			if args[1] == "0" || i > 0 && dump[i-2] == args[1] {
+				iter, _ := strconv.ParseInt(dump[i], 10, 64)
+				if iter == 0 {
+					return []interface{}{int64(0), nil}
+				}
+				return []interface{}{iter, dump[i+1]}
+			}
+		}
+		return []interface{}{int64(0), nil}
+	})
+	s.Reply("BF.LOADCHUNK", func(args []string) interface{} {
+		lock.Lock()
+		defer lock.Unlock()
+
+		chunks[args[0]] = append(chunks[args[0]], args[1], args[2])
+		return redistest.Status("OK")
+	})
+
+	f := NewBloomFilter(client)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	assert.Nil(t, f.Dump(ctx, "src", &buf))
+	dump := buf.Bytes()
+	assert.Nil(t, f.Restore(ctx, "dst", &buf))
+
+	lock.Lock()
+	assert.Equal(t, []string{"1", "header", "1025", "\x00bits\xff"}, chunks["dst"])
+	chunks["dst"] = append(chunks["dst"], "0", "")
+	lock.Unlock()
+
+	// the restored filter dumps the same data
+	var restored bytes.Buffer
+	assert.Nil(t, f.Dump(ctx, "dst", &restored))
+	assert.Equal(t, dump, restored.Bytes())
+
+	assert.Equal(t, [][]string{
+		{"BF.SCANDUMP", "src", "0"},
+		{"BF.SCANDUMP", "src", "1"},
+		{"BF.SCANDUMP", "src", "1025"},
+		{"BF.LOADCHUNK", "dst", "1", "header"},
+		{"BF.LOADCHUNK", "dst", "1025", "\x00bits\xff"},
+		{"BF.SCANDUMP", "dst", "0"},
+		{"BF.SCANDUMP", "dst", "1"},
+		{"BF.SCANDUMP", "dst", "1025"},
+	}, s.Calls())
+
+	// a truncated dump is not restored
+	assert.Equal(t, errInvalidDump, f.Restore(ctx, "bad", bytes.NewReader(dump[:len(dump)-1])))
+}
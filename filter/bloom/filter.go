@@ -0,0 +1,24 @@
+package bloom
+
+import "context"
+
+// Filter is the API shared by the Bloom filters of this package,
+// items of different keys are kept in different filters.
+type Filter interface {
+	// Reserve creates an empty filter of key with the error rate and the capacity.
+	Reserve(ctx context.Context, key string, errorRate float64, capacity int64) error
+	// Add adds item to the filter of key, returns false if item may already exist.
+	Add(ctx context.Context, key, item string) (bool, error)
+	// MAdd adds items to the filter of key, the results are in the order of items.
+	MAdd(ctx context.Context, key string, items ...string) ([]bool, error)
+	// Exists returns false if item definitely does not exist in the filter of key.
+	Exists(ctx context.Context, key, item string) (bool, error)
+	// MExists checks items in the filter of key, the results are in the order of items.
+	MExists(ctx context.Context, key string, items ...string) ([]bool, error)
+}
+
+var (
+	_ Filter = (*BloomFilter)(nil)
+	_ Filter = (*LocalFilter)(nil)
+	_ Filter = (*CachedFilter)(nil)
+)
@@ -0,0 +1,414 @@
+package bloom
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+const (
+	// DefaultErrorRate and DefaultCapacity are used for the filters created
+	// by Add without Reserve, the same as RedisBloom.
+	DefaultErrorRate = 0.01
+	DefaultCapacity  = 100
+	// DefaultExpansion is the expansion of the filters created by LocalFilter.
+	DefaultExpansion = 2
+
+	// tighteningRatio tightens the error rate of every sub-filter so that
+	// the compound error rate stays below the requested one.
+	tighteningRatio = 0.5
+	// maxHashes bounds the hashes of a sub-filter, about an error rate of 1e-19.
+	maxHashes = 64
+
+	localMagic   = "BLM"
+	localVersion = 1
+)
+
+var (
+	ErrKeyExists     = errors.New("bloom: key already exists")
+	ErrFilterFull    = errors.New("bloom: non-scaling filter is full")
+	ErrInvalidFormat = errors.New("bloom: invalid binary format")
+)
+
+// LocalFilter is an in-process Filter, every key holds a LocalBloom.
+type LocalFilter struct {
+	lock    sync.RWMutex
+	filters map[string]*LocalBloom
+}
+
+func NewLocalFilter() *LocalFilter {
+	return &LocalFilter{
+		filters: map[string]*LocalBloom{},
+	}
+}
+
+// Reserve creates an empty scaling filter of key, returns ErrKeyExists if key exists.
+func (f *LocalFilter) Reserve(ctx context.Context, key string, errorRate float64, capacity int64) error {
+	b, err := NewLocalBloom(errorRate, capacity, DefaultExpansion)
+	if err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if _, ok := f.filters[key]; ok {
+		return ErrKeyExists
+	}
+	f.filters[key] = b
+	return nil
+}
+
+func (f *LocalFilter) Add(ctx context.Context, key, item string) (bool, error) {
+	return f.getOrCreate(key).Add([]byte(item))
+}
+
+func (f *LocalFilter) MAdd(ctx context.Context, key string, items ...string) ([]bool, error) {
+	b := f.getOrCreate(key)
+	added := make([]bool, len(items))
+	for i, item := range items {
+		ok, err := b.Add([]byte(item))
+		if err != nil {
+			return nil, err
+		}
+		added[i] = ok
+	}
+	return added, nil
+}
+
+func (f *LocalFilter) Exists(ctx context.Context, key, item string) (bool, error) {
+	b := f.Get(key)
+	if b == nil {
+		return false, nil
+	}
+	return b.Exists([]byte(item)), nil
+}
+
+func (f *LocalFilter) MExists(ctx context.Context, key string, items ...string) ([]bool, error) {
+	exists := make([]bool, len(items))
+	b := f.Get(key)
+	if b == nil {
+		return exists, nil
+	}
+	for i, item := range items {
+		exists[i] = b.Exists([]byte(item))
+	}
+	return exists, nil
+}
+
+// Get returns the filter of key, or nil if key does not exist.
+func (f *LocalFilter) Get(key string) *LocalBloom {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.filters[key]
+}
+
+// Set replaces the filter of key with b, it is used to load a filter built
+// offline. A nil b deletes the key.
+func (f *LocalFilter) Set(key string, b *LocalBloom) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if b == nil {
+		delete(f.filters, key)
+		return
+	}
+	f.filters[key] = b
+}
+
+func (f *LocalFilter) getOrCreate(key string) *LocalBloom {
+	if b := f.Get(key); b != nil {
+		return b
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	b, ok := f.filters[key]
+	if !ok {
+		b, _ = NewLocalBloom(DefaultErrorRate, DefaultCapacity, DefaultExpansion)
+		f.filters[key] = b
+	}
+	return b
+}
+
+// LocalBloom is a scalable Bloom filter, a sub-filter with expansion times
+// the capacity of the last one is created when the last one is full.
+type LocalBloom struct {
+	lock      sync.RWMutex
+	expansion int
+	filters   []*subFilter
+}
+
+type subFilter struct {
+	capacity  int64
+	items     int64
+	errorRate float64
+	hashes    uint32
+	bits      uint64
+	words     []uint64
+}
+
+// NewLocalBloom creates an empty filter. The filter does not scale
+// and Add returns ErrFilterFull when it is full if expansion is 0.
+func NewLocalBloom(errorRate float64, capacity int64, expansion int) (*LocalBloom, error) {
+	if errorRate <= 0 || errorRate >= 1 {
+		return nil, errors.New("bloom: error rate must be between 0 and 1")
+	}
+	if capacity <= 0 {
+		return nil, errors.New("bloom: capacity must be positive")
+	}
+	if expansion < 0 {
+		return nil, errors.New("bloom: expansion must not be negative")
+	}
+	if _, hashes := filterSize(errorRate, capacity); hashes > maxHashes {
+		return nil, errors.New("bloom: error rate is too small")
+	}
+
+	return &LocalBloom{
+		expansion: expansion,
+		filters:   []*subFilter{newSubFilter(errorRate, capacity)},
+	}, nil
+}
+
+func newSubFilter(errorRate float64, capacity int64) *subFilter {
+	bits, hashes := filterSize(errorRate, capacity)
+	return &subFilter{
+		capacity:  capacity,
+		errorRate: errorRate,
+		hashes:    hashes,
+		bits:      bits,
+		words:     make([]uint64, (bits+63)/64),
+	}
+}
+
+// filterSize sizes the filter optimally, m = -n*ln(p)/ln(2)^2 and k = m/n*ln(2).
+func filterSize(errorRate float64, capacity int64) (uint64, uint32) {
+	bitsPerItem := -math.Log(errorRate) / (math.Ln2 * math.Ln2)
+	return uint64(math.Ceil(float64(capacity) * bitsPerItem)), uint32(math.Ceil(math.Ln2 * bitsPerItem))
+}
+
+// locations derives the k bit positions by double hashing, g(i) = h1 + i*h2.
+func locations(item []byte) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write(item)
+	sum := h.Sum(nil)
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
+
+func (s *subFilter) test(h1, h2 uint64) bool {
+	for i := uint64(0); i < uint64(s.hashes); i++ {
+		pos := (h1 + i*h2) % s.bits
+		if s.words[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *subFilter) set(h1, h2 uint64) {
+	for i := uint64(0); i < uint64(s.hashes); i++ {
+		pos := (h1 + i*h2) % s.bits
+		s.words[pos/64] |= 1 << (pos % 64)
+	}
+	s.items++
+}
+
+// Add adds item to the filter, returns false if item may already exist.
+func (b *LocalBloom) Add(item []byte) (bool, error) {
+	h1, h2 := locations(item)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.exists(h1, h2) {
+		return false, nil
+	}
+
+	last := b.filters[len(b.filters)-1]
+	if last.items >= last.capacity {
+		if b.expansion == 0 {
+			return false, ErrFilterFull
+		}
+		// the error rate is not tightened past maxHashes
+		errorRate := last.errorRate * tighteningRatio
+		if _, hashes := filterSize(errorRate, 1); hashes > maxHashes {
+			errorRate = last.errorRate
+		}
+		last = newSubFilter(errorRate, last.capacity*int64(b.expansion))
+		b.filters = append(b.filters, last)
+	}
+	last.set(h1, h2)
+	return true, nil
+}
+
+// Exists returns false if item definitely does not exist in the filter.
+func (b *LocalBloom) Exists(item []byte) bool {
+	h1, h2 := locations(item)
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	return b.exists(h1, h2)
+}
+
+func (b *LocalBloom) exists(h1, h2 uint64) bool {
+	for _, s := range b.filters {
+		if s.test(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Info returns the information of the filter in the same form as BF.INFO.
+func (b *LocalBloom) Info() Info {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	info := Info{
+		Filters:   int64(len(b.filters)),
+		Expansion: int64(b.expansion),
+	}
+	for _, s := range b.filters {
+		info.Capacity += s.capacity
+		info.Items += s.items
+		info.Size += int64(len(s.words) * 8)
+	}
+	return info
+}
+
+// MarshalBinary encodes the filter as the magic "BLM", the version,
+// the expansion and the number of sub-filters, followed by every sub-filter
+// encoded as its capacity, items, error rate, hashes, bits and the bit words.
+// All the integers are big endian.
+func (b *LocalBloom) MarshalBinary() ([]byte, error) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	size := len(localMagic) + 1 + 4 + 4
+	for _, s := range b.filters {
+		size += 8 + 8 + 8 + 4 + 8 + len(s.words)*8
+	}
+
+	buf := make([]byte, 0, size)
+	buf = append(buf, localMagic...)
+	buf = append(buf, localVersion)
+	buf = appendUint32(buf, uint32(b.expansion))
+	buf = appendUint32(buf, uint32(len(b.filters)))
+	for _, s := range b.filters {
+		buf = appendUint64(buf, uint64(s.capacity))
+		buf = appendUint64(buf, uint64(s.items))
+		buf = appendUint64(buf, math.Float64bits(s.errorRate))
+		buf = appendUint32(buf, s.hashes)
+		buf = appendUint64(buf, s.bits)
+		for _, w := range s.words {
+			buf = appendUint64(buf, w)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes the filter encoded by MarshalBinary into b.
+func (b *LocalBloom) UnmarshalBinary(data []byte) error {
+	r := &binaryReader{data: data}
+	if string(r.next(len(localMagic))) != localMagic || r.byte() != localVersion {
+		return ErrInvalidFormat
+	}
+
+	expansion := r.uint32()
+	n := r.uint32()
+	if r.err || n == 0 {
+		return ErrInvalidFormat
+	}
+
+	var filters []*subFilter
+	for i := uint32(0); i < n; i++ {
+		s := &subFilter{
+			capacity:  int64(r.uint64()),
+			items:     int64(r.uint64()),
+			errorRate: math.Float64frombits(r.uint64()),
+			hashes:    r.uint32(),
+			bits:      r.uint64(),
+		}
+		if r.err || s.bits == 0 || (s.bits+63)/64 > uint64(len(r.data))/8 {
+			return ErrInvalidFormat
+		}
+		if s.hashes == 0 || s.hashes > maxHashes || !(s.errorRate > 0 && s.errorRate < 1) ||
+			s.capacity <= 0 || s.items < 0 || s.items > s.capacity {
+			return ErrInvalidFormat
+		}
+		// the size must be the one the filter is created with
+		if bits, hashes := filterSize(s.errorRate, s.capacity); s.bits != bits || s.hashes != hashes {
+			return ErrInvalidFormat
+		}
+		s.words = make([]uint64, (s.bits+63)/64)
+		for j := range s.words {
+			s.words[j] = r.uint64()
+		}
+		filters = append(filters, s)
+	}
+	if r.err || len(r.data) != 0 {
+		return ErrInvalidFormat
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.expansion = int(expansion)
+	b.filters = filters
+	return nil
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+// binaryReader reads big endian integers, err is set once data is too short.
+type binaryReader struct {
+	data []byte
+	err  bool
+}
+
+func (r *binaryReader) next(n int) []byte {
+	if r.err || len(r.data) < n {
+		r.err = true
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *binaryReader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *binaryReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *binaryReader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
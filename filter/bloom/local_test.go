@@ -0,0 +1,139 @@
+package bloom
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalBloom(t *testing.T) {
+	b, err := NewLocalBloom(0.01, 1000, 2)
+	assert.Nil(t, err)
+
+	for i := 0; i < 5000; i++ {
+		_, err := b.Add([]byte(strconv.Itoa(i)))
+		assert.Nil(t, err)
+	}
+	for i := 0; i < 5000; i++ {
+		assert.True(t, b.Exists([]byte(strconv.Itoa(i))))
+	}
+
+	info := b.Info()
+	assert.Equal(t, int64(3), info.Filters)
+	assert.Equal(t, int64(7000), info.Capacity)
+
+	// the compound error rate of the sub-filters is below 2 * 0.01
+	var fp int
+	for i := 5000; i < 15000; i++ {
+		if b.Exists([]byte(strconv.Itoa(i))) {
+			fp++
+		}
+	}
+	assert.True(t, fp < 200, "false positives: %d", fp)
+
+	added, err := b.Add([]byte("1"))
+	assert.Nil(t, err)
+	assert.False(t, added)
+}
+
+func TestLocalBloomNonScaling(t *testing.T) {
+	b, err := NewLocalBloom(0.001, 10, 0)
+	assert.Nil(t, err)
+
+	var full bool
+	for i := 0; i < 20; i++ {
+		if _, err := b.Add([]byte(strconv.Itoa(i))); err != nil {
+			assert.Equal(t, ErrFilterFull, err)
+			full = true
+			break
+		}
+	}
+	assert.True(t, full)
+	assert.Equal(t, int64(1), b.Info().Filters)
+
+	_, err = NewLocalBloom(1, 10, 0)
+	assert.NotNil(t, err)
+	_, err = NewLocalBloom(1e-30, 10, 0)
+	assert.NotNil(t, err)
+}
+
+func TestLocalBloomMarshal(t *testing.T) {
+	b, _ := NewLocalBloom(0.01, 10, 2)
+	for i := 0; i < 30; i++ {
+		b.Add([]byte(strconv.Itoa(i)))
+	}
+
+	data, err := b.MarshalBinary()
+	assert.Nil(t, err)
+
+	c := &LocalBloom{}
+	assert.Nil(t, c.UnmarshalBinary(data))
+	assert.Equal(t, b.Info(), c.Info())
+	for i := 0; i < 30; i++ {
+		assert.True(t, c.Exists([]byte(strconv.Itoa(i))))
+	}
+
+	assert.Equal(t, ErrInvalidFormat, c.UnmarshalBinary(data[:len(data)-1]))
+	assert.Equal(t, ErrInvalidFormat, c.UnmarshalBinary([]byte("BLM")))
+
+	// the fields of the first sub filter follow the magic, version,
+	// expansion and the number of sub filters
+	const (
+		capacityAt  = len(localMagic) + 1 + 4 + 4
+		itemsAt     = capacityAt + 8
+		errorRateAt = itemsAt + 8
+		hashesAt    = errorRateAt + 8
+		bitsAt      = hashesAt + 4
+	)
+	corrupt := func(f func(data []byte)) []byte {
+		corrupted := append([]byte(nil), data...)
+		f(corrupted)
+		return corrupted
+	}
+	for _, bad := range [][]byte{
+		corrupt(func(d []byte) { binary.BigEndian.PutUint32(d[hashesAt:], 0) }),
+		corrupt(func(d []byte) { binary.BigEndian.PutUint64(d[errorRateAt:], math.Float64bits(0)) }),
+		corrupt(func(d []byte) { binary.BigEndian.PutUint64(d[errorRateAt:], math.Float64bits(1)) }),
+		corrupt(func(d []byte) { binary.BigEndian.PutUint64(d[errorRateAt:], math.Float64bits(math.NaN())) }),
+		corrupt(func(d []byte) { binary.BigEndian.PutUint64(d[capacityAt:], 0) }),
+		corrupt(func(d []byte) { binary.BigEndian.PutUint64(d[itemsAt:], 11) }),
+		corrupt(func(d []byte) { binary.BigEndian.PutUint32(d[hashesAt:], 65) }),
+		corrupt(func(d []byte) { binary.BigEndian.PutUint32(d[hashesAt:], 8) }),
+		// the bit length does not match the capacity and the error rate
+		corrupt(func(d []byte) { binary.BigEndian.PutUint64(d[capacityAt:], 9) }),
+		corrupt(func(d []byte) { binary.BigEndian.PutUint64(d[errorRateAt:], math.Float64bits(0.001)) }),
+		corrupt(func(d []byte) { binary.BigEndian.PutUint64(d[bitsAt:], 64) }),
+	} {
+		assert.Equal(t, ErrInvalidFormat, c.UnmarshalBinary(bad))
+	}
+	assert.Nil(t, c.UnmarshalBinary(data))
+}
+
+func TestLocalFilter(t *testing.T) {
+	var f Filter = NewLocalFilter()
+	ctx := context.Background()
+
+	assert.Nil(t, f.Reserve(ctx, "a", 0.01, 100))
+	assert.Equal(t, ErrKeyExists, f.Reserve(ctx, "a", 0.01, 100))
+
+	added, err := f.MAdd(ctx, "a", "x", "y", "x")
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, true, false}, added)
+
+	exists, err := f.MExists(ctx, "a", "x", "z")
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, false}, exists)
+
+	ok, err := f.Exists(ctx, "b", "x")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ok, err = f.Add(ctx, "b", "x")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(DefaultCapacity), f.(*LocalFilter).Get("b").Info().Capacity)
+}
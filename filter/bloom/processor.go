@@ -0,0 +1,33 @@
+package bloom
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/shima-park/tools/concurrent/retry"
+	"github.com/shima-park/tools/filter/internal/redisutil"
+)
+
+// processor processes the commands of the filters with an optional retry policy.
+type processor struct {
+	proc *redisutil.Processor
+}
+
+func newProcessor(client redis.UniversalClient) processor {
+	return processor{proc: redisutil.New(client)}
+}
+
+// SetRetryPolicy retries the reads of the filter failed by network errors.
+func (p *processor) SetRetryPolicy(policy retry.Policy) {
+	p.proc.SetRetryPolicy(policy)
+}
+
+func (p *processor) process(ctx context.Context, cmd redis.Cmder) error {
+	return p.proc.Process(ctx, cmd)
+}
+
+// processPipeline processes cmds in pipelines, split per slot for a cluster client.
+// Returns the first error of cmds.
+func (p *processor) processPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return p.proc.ProcessPipeline(ctx, cmds)
+}
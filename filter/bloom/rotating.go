@@ -0,0 +1,300 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const day = 24 * time.Hour
+
+type RotatingConfig struct {
+	Name      string        // prefix of the bucket keys
+	Bucket    time.Duration // duration of a bucket, default a day
+	Window    time.Duration // items are seen within the window, rounded up to buckets
+	ErrorRate float64       // error rate of every bucket, default DefaultErrorRate
+	Capacity  int64         // capacity of every bucket, default DefaultCapacity
+	// Ahead is the number of buckets reserved ahead by Run, default 1.
+	Ahead int
+	// Layout formats the start of a bucket in its key, default "2006-01-02"
+	// for the buckets of whole days, otherwise "2006-01-02T15:04".
+	Layout string
+	// Location the buckets of whole days are aligned and named in, default
+	// time.Local. The buckets shorter than a day are aligned and named in UTC,
+	// so their edges are not shifted by daylight saving time.
+	Location *time.Location
+}
+
+// RotatingBloomFilter answers whether an item was seen within the window.
+// Every bucket of time has its own filter with key "name:<bucket start>",
+// such as "name:2026-10-18", which expires once it leaves the window.
+type RotatingBloomFilter struct {
+	filter *BloomFilter
+	config RotatingConfig
+
+	lock     sync.Mutex
+	reserved map[string]time.Time // key => bucket start
+}
+
+func NewRotatingBloomFilter(filter *BloomFilter, config RotatingConfig) (*RotatingBloomFilter, error) {
+	if config.Name == "" {
+		return nil, errors.New("bloom: must provide name for rotating filter")
+	}
+	if config.Bucket <= 0 {
+		config.Bucket = day
+	}
+	if config.Bucket%day != 0 && day%config.Bucket != 0 {
+		return nil, errors.New("bloom: bucket must divide or be a multiple of a day")
+	}
+	if config.Window < config.Bucket {
+		return nil, errors.New("bloom: window must not be shorter than bucket")
+	}
+	if config.ErrorRate <= 0 {
+		config.ErrorRate = DefaultErrorRate
+	}
+	if config.Capacity <= 0 {
+		config.Capacity = DefaultCapacity
+	}
+	if config.Ahead <= 0 {
+		config.Ahead = 1
+	}
+	if config.Layout == "" {
+		config.Layout = "2006-01-02"
+		if config.Bucket%day != 0 {
+			config.Layout = "2006-01-02T15:04"
+		}
+	}
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+
+	return &RotatingBloomFilter{
+		filter:   filter,
+		config:   config,
+		reserved: map[string]time.Time{},
+	}, nil
+}
+
+// buckets returns the number of buckets in the window.
+func (f *RotatingBloomFilter) buckets() int {
+	return int((f.config.Window + f.config.Bucket - 1) / f.config.Bucket)
+}
+
+// bucketStart aligns t to the start of its bucket, the buckets of whole
+// days start at midnight in the location, the shorter ones are aligned in UTC.
+func (f *RotatingBloomFilter) bucketStart(t time.Time) time.Time {
+	if f.config.Bucket%day != 0 {
+		return t.UTC().Truncate(f.config.Bucket)
+	}
+
+	t = t.In(f.config.Location)
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, f.config.Location)
+
+	days := int(f.config.Bucket / day)
+	epochDays := int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / int64(day/time.Second))
+	return midnight.AddDate(0, 0, -(epochDays % days))
+}
+
+// shift returns the start of the bucket n buckets after the one starting at start.
+func (f *RotatingBloomFilter) shift(start time.Time, n int) time.Time {
+	if f.config.Bucket%day == 0 {
+		return start.AddDate(0, 0, n*int(f.config.Bucket/day))
+	}
+	return start.Add(time.Duration(n) * f.config.Bucket)
+}
+
+func (f *RotatingBloomFilter) key(start time.Time) string {
+	return f.config.Name + ":" + start.Format(f.config.Layout)
+}
+
+// Keys returns the keys of the buckets in the window at t, the current one first.
+func (f *RotatingBloomFilter) Keys(t time.Time) []string {
+	start := f.bucketStart(t)
+	keys := make([]string, f.buckets())
+	for i := range keys {
+		keys[i] = f.key(f.shift(start, -i))
+	}
+	return keys
+}
+
+// expireAt returns the time the bucket starting at start leaves the window.
+func (f *RotatingBloomFilter) expireAt(start time.Time) time.Time {
+	return f.shift(start, f.buckets())
+}
+
+// Reserve reserves the current bucket and the Ahead buckets after it,
+// and sets the expiration of them. It is called by Run periodically.
+func (f *RotatingBloomFilter) Reserve(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := f.bucketStart(time.Now())
+	for i := 0; i <= f.config.Ahead; i++ {
+		if err := f.reserve(ctx, f.shift(start, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reserve creates the filter of the bucket once, the filter created by
+// another process is reused.
+func (f *RotatingBloomFilter) reserve(ctx context.Context, start time.Time) error {
+	key := f.key(start)
+
+	f.lock.Lock()
+	_, ok := f.reserved[key]
+	f.lock.Unlock()
+	if ok {
+		return nil
+	}
+
+	err := f.filter.Reserve(ctx, key, f.config.ErrorRate, f.config.Capacity)
+	if err != nil && !strings.Contains(err.Error(), "item exists") {
+		return err
+	}
+
+	cmd := redis.NewBoolCmd(ctx, "PEXPIREAT", key, f.expireAt(start).UnixNano()/int64(time.Millisecond))
+	f.filter.process(ctx, cmd)
+	if err := cmd.Err(); err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.reserved[key] = start
+	f.forget(f.bucketStart(time.Now()))
+	return nil
+}
+
+// forget drops the reserved buckets before current, it is called whenever
+// a bucket is reserved so the buckets left the window do not pile up.
+// It must be called with the lock held.
+func (f *RotatingBloomFilter) forget(current time.Time) {
+	for key, start := range f.reserved {
+		if start.Before(current) {
+			delete(f.reserved, key)
+		}
+	}
+}
+
+// Run calls Reserve every interval until ctx is done,
+// errors are passed to errHandle if it is not nil.
+func (f *RotatingBloomFilter) Run(ctx context.Context, interval time.Duration, errHandle func(error)) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.Reserve(ctx); err != nil && errHandle != nil {
+			errHandle(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Exists returns whether item may have been added within the window,
+// the buckets are checked in one pipeline.
+func (f *RotatingBloomFilter) Exists(ctx context.Context, item string) (bool, error) {
+	exists, err := f.MExists(ctx, item)
+	if err != nil {
+		return false, err
+	}
+	return exists[0], nil
+}
+
+// MExists checks items in every bucket of the window in one pipeline.
+func (f *RotatingBloomFilter) MExists(ctx context.Context, items ...string) ([]bool, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	keys := f.Keys(time.Now())
+	cmds := make([]redis.Cmder, len(keys))
+	for i, key := range keys {
+		cmds[i] = newItemsCmd(ctx, "BF.MEXISTS", key, items)
+	}
+	if err := f.filter.processPipeline(ctx, cmds); err != nil {
+		return nil, err
+	}
+
+	return mergeResults(cmds, len(items)), nil
+}
+
+// Add adds item into the current bucket, returns false if item may have been
+// added within the window. The item stays in the window for another window
+// even if it existed.
+func (f *RotatingBloomFilter) Add(ctx context.Context, item string) (bool, error) {
+	added, err := f.MAdd(ctx, item)
+	if err != nil {
+		return false, err
+	}
+	return added[0], nil
+}
+
+// MAdd adds items into the current bucket and checks them in the other
+// buckets of the window in one pipeline.
+func (f *RotatingBloomFilter) MAdd(ctx context.Context, items ...string) ([]bool, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	now := time.Now()
+	if err := f.reserve(ctx, f.bucketStart(now)); err != nil {
+		return nil, err
+	}
+
+	keys := f.Keys(now)
+	cmds := make([]redis.Cmder, len(keys))
+	cmds[0] = newItemsCmd(ctx, "BF.MADD", keys[0], items)
+	for i := 1; i < len(keys); i++ {
+		cmds[i] = newItemsCmd(ctx, "BF.MEXISTS", keys[i], items)
+	}
+	if err := f.filter.processPipeline(ctx, cmds); err != nil {
+		return nil, err
+	}
+
+	existed := mergeResults(cmds[1:], len(items))
+	added := cmds[0].(*redis.BoolSliceCmd).Val()
+	for i := range existed {
+		existed[i] = added[i] && !existed[i]
+	}
+	return existed, nil
+}
+
+func newItemsCmd(ctx context.Context, name, key string, items []string) *redis.BoolSliceCmd {
+	args := make([]interface{}, 0, len(items)+2)
+	args = append(args, name, key)
+	for _, item := range items {
+		args = append(args, item)
+	}
+	return redis.NewBoolSliceCmd(ctx, args...)
+}
+
+// mergeResults ors the results of the BoolSliceCmds.
+func mergeResults(cmds []redis.Cmder, n int) []bool {
+	merged := make([]bool, n)
+	for _, cmd := range cmds {
+		for i, ok := range cmd.(*redis.BoolSliceCmd).Val() {
+			if i < n && ok {
+				merged[i] = true
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,217 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shima-park/tools/filter/internal/redistest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingKeys(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	f, err := NewRotatingBloomFilter(nil, RotatingConfig{
+		Name:     "seen",
+		Window:   3 * day,
+		Location: loc,
+	})
+	assert.Nil(t, err)
+
+	now := time.Date(2026, 10, 18, 0, 30, 0, 0, loc)
+	assert.Equal(t, []string{"seen:2026-10-18", "seen:2026-10-17", "seen:2026-10-16"}, f.Keys(now))
+	assert.Equal(t, []string{"seen:2026-10-18", "seen:2026-10-17", "seen:2026-10-16"}, f.Keys(now.UTC()))
+
+	start := f.bucketStart(now)
+	assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, loc), start)
+	assert.Equal(t, time.Date(2026, 10, 21, 0, 0, 0, 0, loc), f.expireAt(start))
+}
+
+func TestRotatingBuckets(t *testing.T) {
+	f, err := NewRotatingBloomFilter(nil, RotatingConfig{
+		Name:     "seen",
+		Bucket:   6 * time.Hour,
+		Window:   13 * time.Hour,
+		Location: time.UTC,
+	})
+	assert.Nil(t, err)
+
+	now := time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"seen:2026-10-18T00:00", "seen:2026-10-17T18:00", "seen:2026-10-17T12:00"}, f.Keys(now))
+
+	f, err = NewRotatingBloomFilter(nil, RotatingConfig{
+		Name:     "seen",
+		Bucket:   2 * day,
+		Window:   4 * day,
+		Location: time.UTC,
+	})
+	assert.Nil(t, err)
+	// the buckets of 2 days are aligned to the unix epoch
+	assert.Equal(t, []string{"seen:2026-10-18", "seen:2026-10-16"}, f.Keys(now))
+	assert.Equal(t, []string{"seen:2026-10-18", "seen:2026-10-16"}, f.Keys(now.AddDate(0, 0, 1)))
+	assert.Equal(t, []string{"seen:2026-10-20", "seen:2026-10-18"}, f.Keys(now.AddDate(0, 0, 2)))
+
+	_, err = NewRotatingBloomFilter(nil, RotatingConfig{Name: "seen", Bucket: 7 * time.Hour, Window: day})
+	assert.NotNil(t, err)
+	_, err = NewRotatingBloomFilter(nil, RotatingConfig{Name: "seen", Window: time.Hour})
+	assert.NotNil(t, err)
+}
+
+func TestRotatingBucketsDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	f, err := NewRotatingBloomFilter(nil, RotatingConfig{
+		Name:     "seen",
+		Bucket:   time.Hour,
+		Window:   3 * time.Hour,
+		Location: loc,
+	})
+	assert.Nil(t, err)
+
+	// 01:00-02:00 happens twice on the day daylight saving time ends
+	first := time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	assert.Equal(t, "01:30", first.In(loc).Format("15:04"))
+	assert.Equal(t, "01:30", second.In(loc).Format("15:04"))
+
+	assert.Equal(t, []string{"seen:2026-11-01T05:00", "seen:2026-11-01T04:00", "seen:2026-11-01T03:00"}, f.Keys(first))
+	assert.Equal(t, []string{"seen:2026-11-01T06:00", "seen:2026-11-01T05:00", "seen:2026-11-01T04:00"}, f.Keys(second))
+	assert.Equal(t, time.Hour, f.bucketStart(second).Sub(f.bucketStart(first)))
+}
+
+// fakeBloom serves the BF commands used by RotatingBloomFilter with sets.
+type fakeBloom struct {
+	lock sync.Mutex
+	sets map[string]map[string]bool
+}
+
+func newFakeBloom(s *redistest.Server) *fakeBloom {
+	b := &fakeBloom{sets: map[string]map[string]bool{}}
+	s.Reply("BF.RESERVE", func(args []string) interface{} {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		if _, ok := b.sets[args[0]]; ok {
+			return errors.New("ERR item exists")
+		}
+		b.sets[args[0]] = map[string]bool{}
+		// a real key to expire
+		s.Set(args[0], "bloom")
+		return redistest.Status("OK")
+	})
+	s.Reply("BF.MADD", func(args []string) interface{} {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		set, ok := b.sets[args[0]]
+		if !ok {
+			set = map[string]bool{}
+			b.sets[args[0]] = set
+		}
+		var reply []interface{}
+		for _, item := range args[1:] {
+			if set[item] {
+				reply = append(reply, int64(0))
+				continue
+			}
+			set[item] = true
+			reply = append(reply, int64(1))
+		}
+		return reply
+	})
+	s.Reply("BF.MEXISTS", func(args []string) interface{} {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		var reply []interface{}
+		for _, item := range args[1:] {
+			if b.sets[args[0]][item] {
+				reply = append(reply, int64(1))
+			} else {
+				reply = append(reply, int64(0))
+			}
+		}
+		return reply
+	})
+	return b
+}
+
+func TestRotatingBloomFilter(t *testing.T) {
+	s, client := redistest.NewServer(t)
+	b := newFakeBloom(s)
+	ctx := context.Background()
+
+	f, err := NewRotatingBloomFilter(NewBloomFilter(client), RotatingConfig{
+		Name:     "seen",
+		Bucket:   time.Hour,
+		Window:   3 * time.Hour,
+		Capacity: 1000,
+	})
+	assert.Nil(t, err)
+
+	now := time.Now()
+	keys := f.Keys(now)
+	// added two buckets ago
+	b.sets[keys[2]] = map[string]bool{"old": true}
+
+	added, err := f.MAdd(ctx, "new", "old", "new")
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, false, false}, added)
+
+	exists, err := f.MExists(ctx, "old", "new", "none")
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, true, false}, exists)
+
+	ok, err := f.Add(ctx, "new")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, [][]string{
+		{"BF.RESERVE", keys[0], "0.01", "1000"},
+		{"BF.MADD", keys[0], "new", "old", "new"},
+		{"BF.MEXISTS", keys[1], "new", "old", "new"},
+		{"BF.MEXISTS", keys[2], "new", "old", "new"},
+		{"BF.MEXISTS", keys[0], "old", "new", "none"},
+		{"BF.MEXISTS", keys[1], "old", "new", "none"},
+		{"BF.MEXISTS", keys[2], "old", "new", "none"},
+		{"BF.MADD", keys[0], "new"},
+		{"BF.MEXISTS", keys[1], "new"},
+		{"BF.MEXISTS", keys[2], "new"},
+	}, s.Calls())
+
+	// the bucket expires once it leaves the window
+	start := f.bucketStart(now)
+	assert.InDelta(t, time.Until(start.Add(3*time.Hour)), s.TTL(keys[0]), float64(time.Second))
+
+	// the next bucket is reserved ahead
+	assert.Nil(t, f.Reserve(ctx))
+	next := f.key(start.Add(time.Hour))
+	assert.Contains(t, s.Calls(), []string{"BF.RESERVE", next, "0.01", "1000"})
+	assert.InDelta(t, time.Until(start.Add(4*time.Hour)), s.TTL(next), float64(time.Second))
+}
+
+func TestRotatingForget(t *testing.T) {
+	s, client := redistest.NewServer(t)
+	newFakeBloom(s)
+
+	f, err := NewRotatingBloomFilter(NewBloomFilter(client), RotatingConfig{
+		Name:   "seen",
+		Bucket: time.Hour,
+		Window: 3 * time.Hour,
+	})
+	assert.Nil(t, err)
+
+	start := f.bucketStart(time.Now())
+	stale := f.key(start.Add(-time.Hour))
+	f.reserved[stale] = start.Add(-time.Hour)
+
+	// writing into a new bucket drops the buckets before the current one
+	_, err = f.Add(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]time.Time{f.key(start): start}, f.reserved)
+}
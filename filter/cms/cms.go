@@ -0,0 +1,151 @@
+package cms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/shima-park/tools/concurrent/retry"
+	"github.com/shima-park/tools/filter/internal/redisutil"
+)
+
+// CountMinSketch doc: https://oss.redislabs.com/redisbloom/CountMinSketch_Commands/
+// The client may be a single node, sentinel or cluster client.
+type CountMinSketch struct {
+	proc *redisutil.Processor
+}
+
+func New(client redis.UniversalClient) *CountMinSketch {
+	return &CountMinSketch{proc: redisutil.New(client)}
+}
+
+// SetRetryPolicy retries CMS.QUERY and CMS.INFO failed by network errors.
+func (s *CountMinSketch) SetRetryPolicy(p retry.Policy) {
+	s.proc.SetRetryPolicy(p)
+}
+
+// InitByDim Initializes a Count-Min Sketch to dimensions specified by user.
+// key : The name of the sketch
+// width : Number of counters in each array, reduces the error size
+// depth : Number of counter-arrays, reduces the probability for an error of a certain size
+func (s *CountMinSketch) InitByDim(ctx context.Context, key string, width, depth int64) error {
+	cmd := redis.NewStatusCmd(ctx, "CMS.INITBYDIM", key, width, depth)
+	s.proc.Process(ctx, cmd)
+	return cmd.Err()
+}
+
+// InitByProb Initializes a Count-Min Sketch to accommodate requested tolerances.
+// key : The name of the sketch
+// errorRate : Estimate size of error, as a percent of total counted items
+// probability : The desired probability for inflated count, between 0 and 1
+func (s *CountMinSketch) InitByProb(ctx context.Context, key string, errorRate, probability float64) error {
+	cmd := redis.NewStatusCmd(ctx, "CMS.INITBYPROB", key, errorRate, probability)
+	s.proc.Process(ctx, cmd)
+	return cmd.Err()
+}
+
+// Increment is an item and the number to increase its count by.
+type Increment struct {
+	Item string
+	By   int64
+}
+
+// IncrBy Increases the count of items by increments.
+// key : The name of the sketch
+// increments : Items and the increments of their counts
+// Returns the updated counts of the items.
+func (s *CountMinSketch) IncrBy(ctx context.Context, key string, increments ...Increment) ([]int64, error) {
+	args := []interface{}{"CMS.INCRBY", key}
+	for _, incr := range increments {
+		args = append(args, incr.Item, incr.By)
+	}
+	cmd := redis.NewIntSliceCmd(ctx, args...)
+	s.proc.Process(ctx, cmd)
+	return cmd.Result()
+}
+
+// Query Returns the counts of items.
+// key : The name of the sketch
+// items : One or more items for which to return the count
+func (s *CountMinSketch) Query(ctx context.Context, key string, items ...string) ([]int64, error) {
+	args := []interface{}{"CMS.QUERY", key}
+	for _, item := range items {
+		args = append(args, item)
+	}
+	cmd := redis.NewIntSliceCmd(ctx, args...)
+	s.proc.Process(ctx, cmd)
+	return cmd.Result()
+}
+
+// Source is a sketch merged by Merge, its counts are multiplied by Weight.
+// A zero Weight is the same as 1.
+type Source struct {
+	Key    string
+	Weight int64
+}
+
+// Merge Merges several sketches into one sketch. All sketches must have identical width and depth.
+// dest : The name of destination sketch, it must be initialized
+// sources : The sketches to be merged
+func (s *CountMinSketch) Merge(ctx context.Context, dest string, sources ...Source) error {
+	args := []interface{}{"CMS.MERGE", dest, len(sources)}
+	weighted := false
+	for _, src := range sources {
+		args = append(args, src.Key)
+		if src.Weight != 0 && src.Weight != 1 {
+			weighted = true
+		}
+	}
+	if weighted {
+		args = append(args, "WEIGHTS")
+		for _, src := range sources {
+			if src.Weight == 0 {
+				src.Weight = 1
+			}
+			args = append(args, src.Weight)
+		}
+	}
+	cmd := redis.NewStatusCmd(ctx, args...)
+	s.proc.Process(ctx, cmd)
+	return cmd.Err()
+}
+
+// Info is the result of CMS.INFO.
+type Info struct {
+	Width int64
+	Depth int64
+	Count int64 // total count in the sketch
+}
+
+// Info Returns width, depth and total count of the sketch.
+// key : The name of the sketch
+func (s *CountMinSketch) Info(ctx context.Context, key string) (*Info, error) {
+	cmd := redis.NewSliceCmd(ctx, "CMS.INFO", key)
+	s.proc.Process(ctx, cmd)
+	vals, err := cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseInfo(vals)
+}
+
+func parseInfo(vals []interface{}) (*Info, error) {
+	if len(vals)%2 != 0 {
+		return nil, fmt.Errorf("cms: unexpected CMS.INFO reply length %d", len(vals))
+	}
+
+	info := &Info{}
+	for i := 0; i < len(vals); i += 2 {
+		name, _ := vals[i].(string)
+		n, _ := vals[i+1].(int64)
+		switch name {
+		case "width":
+			info.Width = n
+		case "depth":
+			info.Depth = n
+		case "count":
+			info.Count = n
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,58 @@
+package cms
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shima-park/tools/filter/internal/redistest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountMinSketch(t *testing.T) {
+	s, client := redistest.NewServer(t)
+	ok := func(args []string) interface{} { return redistest.Status("OK") }
+	s.Reply("CMS.INITBYDIM", ok)
+	s.Reply("CMS.INITBYPROB", ok)
+	s.Reply("CMS.MERGE", ok)
+	s.Reply("CMS.INCRBY", func(args []string) interface{} { return []interface{}{int64(1), int64(5)} })
+	s.Reply("CMS.QUERY", func(args []string) interface{} { return []interface{}{int64(1), int64(0)} })
+	s.Reply("CMS.INFO", func(args []string) interface{} {
+		return []interface{}{"width", int64(2000), "depth", int64(5), "count", int64(6)}
+	})
+
+	cms := New(client)
+	ctx := context.Background()
+
+	assert.Nil(t, cms.InitByDim(ctx, "k", 2000, 5))
+	assert.Nil(t, cms.InitByProb(ctx, "k", 0.001, 0.01))
+
+	counts, err := cms.IncrBy(ctx, "k", Increment{Item: "a", By: 1}, Increment{Item: "b", By: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 5}, counts)
+
+	counts, err = cms.Query(ctx, "k", "a", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 0}, counts)
+
+	assert.Nil(t, cms.Merge(ctx, "dest", Source{Key: "a"}, Source{Key: "b"}))
+	assert.Nil(t, cms.Merge(ctx, "dest", Source{Key: "a"}, Source{Key: "b", Weight: 3}))
+
+	info, err := cms.Info(ctx, "k")
+	assert.Nil(t, err)
+	assert.Equal(t, &Info{Width: 2000, Depth: 5, Count: 6}, info)
+
+	assert.Equal(t, [][]string{
+		{"CMS.INITBYDIM", "k", "2000", "5"},
+		{"CMS.INITBYPROB", "k", "0.001", "0.01"},
+		{"CMS.INCRBY", "k", "a", "1", "b", "2"},
+		{"CMS.QUERY", "k", "a", "c"},
+		{"CMS.MERGE", "dest", "2", "a", "b"},
+		{"CMS.MERGE", "dest", "2", "a", "b", "WEIGHTS", "1", "3"},
+		{"CMS.INFO", "k"},
+	}, s.Calls())
+}
+
+func TestParseInfo(t *testing.T) {
+	_, err := parseInfo([]interface{}{"width"})
+	assert.NotNil(t, err)
+}
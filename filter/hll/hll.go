@@ -0,0 +1,64 @@
+package hll
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/shima-park/tools/concurrent/retry"
+	"github.com/shima-park/tools/filter/internal/redisutil"
+)
+
+// HyperLogLog estimates the number of unique items with the PF commands of redis.
+// doc: https://redis.io/commands#hyperloglog
+type HyperLogLog struct {
+	proc *redisutil.Processor
+}
+
+func New(client redis.UniversalClient) *HyperLogLog {
+	return &HyperLogLog{proc: redisutil.New(client)}
+}
+
+// SetRetryPolicy retries PFCOUNT and PFMERGE failed by network errors.
+func (h *HyperLogLog) SetRetryPolicy(p retry.Policy) {
+	h.proc.SetRetryPolicy(p)
+}
+
+// Add Adds items to the HyperLogLog of key, creating it if it does not exist.
+// key : The name of the HyperLogLog
+// items : Items to add
+// Returns true if the estimated cardinality was changed.
+func (h *HyperLogLog) Add(ctx context.Context, key string, items ...string) (bool, error) {
+	args := []interface{}{"PFADD", key}
+	for _, item := range items {
+		args = append(args, item)
+	}
+	cmd := redis.NewIntCmd(ctx, args...)
+	h.proc.Process(ctx, cmd)
+	n, err := cmd.Result()
+	return n == 1, err
+}
+
+// Count Returns the estimated cardinality of the union of the HyperLogLogs of keys.
+// keys : One or more names of the HyperLogLogs, all of them must be in the same slot for a cluster
+func (h *HyperLogLog) Count(ctx context.Context, keys ...string) (int64, error) {
+	args := []interface{}{"PFCOUNT"}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	cmd := redis.NewIntCmd(ctx, args...)
+	h.proc.Process(ctx, cmd)
+	return cmd.Result()
+}
+
+// Merge Merges the HyperLogLogs of sources into dest.
+// dest : The name of the destination HyperLogLog, it is created if it does not exist
+// sources : The HyperLogLogs to merge, all of them must be in the same slot as dest for a cluster
+func (h *HyperLogLog) Merge(ctx context.Context, dest string, sources ...string) error {
+	args := []interface{}{"PFMERGE", dest}
+	for _, src := range sources {
+		args = append(args, src)
+	}
+	cmd := redis.NewStatusCmd(ctx, args...)
+	h.proc.Process(ctx, cmd)
+	return cmd.Err()
+}
@@ -0,0 +1,36 @@
+package hll
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shima-park/tools/filter/internal/redistest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	_, client := redistest.NewServer(t)
+	h := New(client)
+	ctx := context.Background()
+
+	changed, err := h.Add(ctx, "a", "x", "y", "z")
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	changed, err = h.Add(ctx, "a", "x")
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	_, err = h.Add(ctx, "b", "z", "w")
+	assert.Nil(t, err)
+
+	n, err := h.Count(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Nil(t, h.Merge(ctx, "dest", "a", "b"))
+	n, err = h.Count(ctx, "dest")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), n)
+
+	n, err = h.Count(ctx, "missing")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), n)
+}
@@ -0,0 +1,88 @@
+// Package redistest serves the redis module commands used by the filter
+// packages with scripted replies for tests.
+package redistest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/go-redis/redis/v8"
+)
+
+// Status is a simple string reply such as OK.
+type Status string
+
+// Server is a miniredis server, the commands it does not support are
+// served by Reply.
+type Server struct {
+	*miniredis.Miniredis
+
+	lock  sync.Mutex
+	calls [][]string
+}
+
+// NewServer runs a server closed when the test finished,
+// and returns a client connected to it.
+func NewServer(t testing.TB) (*Server, *redis.Client) {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		m.Close()
+	})
+	return &Server{Miniredis: m}, client
+}
+
+// Reply serves the command name with the reply returned by fn, which is
+// nil, an int, an int64, a string, a Status, an error or a []interface{}
+// of them. Every call is recorded for Calls.
+func (s *Server) Reply(name string, fn func(args []string) interface{}) {
+	err := s.Server().Register(name, func(peer *server.Peer, cmd string, args []string) {
+		s.lock.Lock()
+		s.calls = append(s.calls, append([]string{strings.ToUpper(cmd)}, args...))
+		s.lock.Unlock()
+
+		write(peer, fn(args))
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Calls returns the command names and arguments of the calls served by Reply.
+func (s *Server) Calls() [][]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([][]string(nil), s.calls...)
+}
+
+func write(peer *server.Peer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		peer.WriteNull()
+	case int:
+		peer.WriteInt(v)
+	case int64:
+		peer.WriteInt(int(v))
+	case string:
+		peer.WriteBulk(v)
+	case Status:
+		peer.WriteInline(string(v))
+	case error:
+		peer.WriteError(v.Error())
+	case []interface{}:
+		peer.WriteLen(len(v))
+		for _, e := range v {
+			write(peer, e)
+		}
+	default:
+		panic("redistest: unsupported reply")
+	}
+}
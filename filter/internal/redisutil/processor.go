@@ -0,0 +1,143 @@
+// Package redisutil processes the redis commands of the filter packages.
+package redisutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/shima-park/tools/concurrent/retry"
+)
+
+// Processor processes the commands of the filters with an optional retry policy.
+type Processor struct {
+	client redis.UniversalClient
+
+	lock  sync.Mutex
+	retry *retry.Policy
+}
+
+func New(client redis.UniversalClient) *Processor {
+	return &Processor{client: client}
+}
+
+// idempotent are the commands which can be retried safely, retrying a write
+// such as BF.ADD whose reply was lost would report the items as existing.
+var idempotent = map[string]bool{
+	"bf.exists": true, "bf.mexists": true, "bf.info": true, "bf.card": true, "bf.scandump": true,
+	"cf.exists": true, "cf.mexists": true, "cf.count": true, "cf.info": true, "cf.scandump": true,
+	"topk.query": true, "topk.count": true, "topk.list": true, "topk.info": true,
+	"cms.query": true, "cms.info": true,
+	"pfcount": true, "pfmerge": true, "pexpireat": true,
+}
+
+// SetRetryPolicy retries the commands failed by network errors with p, the
+// errors replied by redis are never retried. Only the idempotent commands
+// such as the reads are retried, a pipeline is retried as a whole if all of
+// its commands are idempotent.
+func (p *Processor) SetRetryPolicy(policy retry.Policy) {
+	retryable := policy.Retryable
+	policy.Retryable = func(err error) bool {
+		if err == redis.Nil {
+			return false
+		}
+		var redisErr redis.Error
+		if errors.As(err, &redisErr) {
+			return false
+		}
+		return retryable == nil || retryable(err)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.retry = &policy
+}
+
+// retryPolicy returns the retry policy of cmds, nil if they must not be retried.
+func (p *Processor) retryPolicy(cmds ...redis.Cmder) *retry.Policy {
+	for _, cmd := range cmds {
+		if !idempotent[cmd.Name()] {
+			return nil
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.retry
+}
+
+// Process processes cmd, the error of cmd is returned.
+func (p *Processor) Process(ctx context.Context, cmd redis.Cmder) error {
+	policy := p.retryPolicy(cmd)
+	if policy == nil {
+		return p.client.Process(ctx, cmd)
+	}
+	return retry.Do(ctx, *policy, func(ctx context.Context) error {
+		return p.client.Process(ctx, cmd)
+	})
+}
+
+// maxPipelines is the maximum number of the pipelines of a call executed
+// concurrently for a cluster client.
+const maxPipelines = 8
+
+// ProcessPipeline processes cmds in pipelines, the pipeline of idempotent
+// commands is retried as a whole if it failed by network errors. The cmds are split into a pipeline
+// per slot if the client is a cluster client, up to maxPipelines of them are
+// executed concurrently. The results are kept in cmds so their order is unchanged.
+// Returns the first error of cmds.
+func (p *Processor) ProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	if _, ok := p.client.(*redis.ClusterClient); !ok || len(cmds) < 2 {
+		return p.execPipeline(ctx, cmds)
+	}
+
+	groups := groupBySlot(cmds)
+	if len(groups) == 1 {
+		return p.execPipeline(ctx, cmds)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxPipelines)
+	for _, group := range groups {
+		batch := make([]redis.Cmder, len(group))
+		for i, index := range group {
+			batch[i] = cmds[index]
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			_ = p.execPipeline(ctx, batch)
+		}()
+	}
+	wg.Wait()
+
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Processor) execPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	exec := func(ctx context.Context) error {
+		pipe := p.client.Pipeline()
+		for _, cmd := range cmds {
+			_ = pipe.Process(ctx, cmd)
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+
+	policy := p.retryPolicy(cmds...)
+	if policy == nil {
+		return exec(ctx)
+	}
+	return retry.Do(ctx, *policy, exec)
+}
@@ -0,0 +1,153 @@
+package redisutil
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/shima-park/tools/concurrent/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlot(t *testing.T) {
+	assert.Equal(t, uint16(0x31C3), crc16("123456789"))
+	assert.Equal(t, 12182, slot("foo"))
+	assert.Equal(t, slot("user1000"), slot("{user1000}.following"))
+	assert.Equal(t, slot("{}.a"), slot("{}.a"))
+	assert.NotEqual(t, slot("{}.a"), slot("{}.b"))
+
+	ctx := context.Background()
+	cmds := []redis.Cmder{
+		redis.NewBoolSliceCmd(ctx, "BF.MADD", "{a}1", "x"),
+		redis.NewBoolSliceCmd(ctx, "BF.MADD", "b", "x"),
+		redis.NewBoolSliceCmd(ctx, "BF.MADD", "{a}2", "x"),
+	}
+	assert.Equal(t, [][]int{{0, 2}, {1}}, groupBySlot(cmds))
+}
+
+func TestProcessPipeline(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	s.Set("a", "1")
+	s.Set("b", "2")
+
+	p := New(redis.NewClient(&redis.Options{Addr: s.Addr()}))
+	ctx := context.Background()
+	cmds := []redis.Cmder{
+		redis.NewStringCmd(ctx, "GET", "b"),
+		redis.NewStringCmd(ctx, "GET", "a"),
+	}
+	assert.Nil(t, p.ProcessPipeline(ctx, cmds))
+	assert.Equal(t, "2", cmds[0].(*redis.StringCmd).Val())
+	assert.Equal(t, "1", cmds[1].(*redis.StringCmd).Val())
+}
+
+// pipelineHook counts the pipelines and the maximum of them executed concurrently.
+type pipelineHook struct {
+	pipelines int32
+	running   int32
+	max       int32
+}
+
+func (h *pipelineHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *pipelineHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *pipelineHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	atomic.AddInt32(&h.pipelines, 1)
+	n := atomic.AddInt32(&h.running, 1)
+	for {
+		max := atomic.LoadInt32(&h.max)
+		if n <= max || atomic.CompareAndSwapInt32(&h.max, max, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	return ctx, nil
+}
+
+func (h *pipelineHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	atomic.AddInt32(&h.running, -1)
+	return nil
+}
+
+func TestProcessPipelineCluster(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{s.Addr()}})
+	hook := &pipelineHook{}
+	client.AddHook(hook)
+	p := New(client)
+	ctx := context.Background()
+
+	var (
+		cmds  []redis.Cmder
+		slots = map[int]bool{}
+	)
+	for i := 0; i < 40; i++ {
+		key := "key" + strconv.Itoa(i)
+		if i%2 == 1 {
+			// the keys sharing a hash tag are in the same slot
+			key = "{tag}" + key
+		}
+		s.Set(key, strconv.Itoa(i))
+		cmds = append(cmds, redis.NewStringCmd(ctx, "GET", key))
+		slots[slot(key)] = true
+	}
+
+	assert.Nil(t, p.ProcessPipeline(ctx, cmds))
+	for i, cmd := range cmds {
+		assert.Equal(t, strconv.Itoa(i), cmd.(*redis.StringCmd).Val())
+	}
+	assert.Equal(t, int32(len(slots)), atomic.LoadInt32(&hook.pipelines))
+	assert.True(t, atomic.LoadInt32(&hook.max) <= maxPipelines)
+}
+
+// flakyHook fails the first command of every name by a network error.
+type flakyHook struct {
+	pipelineHook
+	calls map[string]int
+}
+
+func (h *flakyHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.calls[cmd.Name()]++
+	if h.calls[cmd.Name()] == 1 {
+		return ctx, errors.New("connection reset by peer")
+	}
+	return ctx, nil
+}
+
+func TestProcessRetry(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	hook := &flakyHook{calls: map[string]int{}}
+	client.AddHook(hook)
+	p := New(client)
+	p.SetRetryPolicy(retry.Constant(time.Millisecond, 3))
+	ctx := context.Background()
+
+	// the write is not retried, it may have been applied before the failure
+	add := redis.NewIntCmd(ctx, "PFADD", "h", "a")
+	assert.NotNil(t, p.Process(ctx, add))
+	assert.Equal(t, 1, hook.calls["pfadd"])
+
+	count := redis.NewIntCmd(ctx, "PFCOUNT", "h")
+	assert.Nil(t, p.Process(ctx, count))
+	assert.Equal(t, 2, hook.calls["pfcount"])
+}
@@ -0,0 +1,61 @@
+package redisutil
+
+import (
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const slotCount = 16384
+
+// slot returns the redis cluster slot of key, only the part between the
+// first "{" and the following "}" is hashed if it is not empty.
+func slot(key string) int {
+	if s := strings.IndexByte(key, '{'); s >= 0 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+1+e]
+		}
+	}
+	return int(crc16(key) % slotCount)
+}
+
+// crc16 is the CRC16-CCITT (XMODEM) checksum used by redis cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// groupBySlot groups the indexes of cmds by the slot of their keys, the key
+// of every filter command is its second argument. The groups are in the
+// order of their first commands.
+func groupBySlot(cmds []redis.Cmder) [][]int {
+	var groups [][]int
+	index := map[int]int{}
+	for i, cmd := range cmds {
+		s := 0
+		if args := cmd.Args(); len(args) > 1 {
+			if key, ok := args[1].(string); ok {
+				s = slot(key)
+			}
+		}
+
+		g, ok := index[s]
+		if !ok {
+			g = len(groups)
+			index[s] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
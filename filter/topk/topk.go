@@ -0,0 +1,153 @@
+package topk
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/shima-park/tools/concurrent/retry"
+	"github.com/shima-park/tools/filter/internal/redisutil"
+)
+
+// TopK doc: https://oss.redislabs.com/redisbloom/TopK_Commands/
+// The client may be a single node, sentinel or cluster client.
+type TopK struct {
+	proc *redisutil.Processor
+}
+
+func New(client redis.UniversalClient) *TopK {
+	return &TopK{proc: redisutil.New(client)}
+}
+
+// SetRetryPolicy retries the reads such as TOPK.QUERY failed by network errors.
+func (t *TopK) SetRetryPolicy(p retry.Policy) {
+	t.proc.SetRetryPolicy(p)
+}
+
+const (
+	DefaultWidth = 8
+	DefaultDepth = 7
+	DefaultDecay = 0.9
+)
+
+// ReserveOptions are the optional arguments of TOPK.RESERVE, they are given
+// together so the zero fields are sent as their defaults.
+type ReserveOptions struct {
+	Width int64   // number of counters kept in each array, default DefaultWidth
+	Depth int64   // number of arrays, default DefaultDepth
+	Decay float64 // probability of reducing a counter in an occupied bucket, default DefaultDecay
+}
+
+// Reserve Initializes a TopK with specified parameters.
+// key : Key under which the sketch is to be found
+// topk : Number of top occurring items to keep
+// opts : Optional arguments, may be nil
+func (t *TopK) Reserve(ctx context.Context, key string, topk int64, opts *ReserveOptions) error {
+	args := []interface{}{"TOPK.RESERVE", key, topk}
+	if opts != nil {
+		width, depth, decay := opts.Width, opts.Depth, opts.Decay
+		if width <= 0 {
+			width = DefaultWidth
+		}
+		if depth <= 0 {
+			depth = DefaultDepth
+		}
+		if decay <= 0 {
+			decay = DefaultDecay
+		}
+		args = append(args, width, depth, decay)
+	}
+	cmd := redis.NewStatusCmd(ctx, args...)
+	t.proc.Process(ctx, cmd)
+	return cmd.Err()
+}
+
+// Add Adds items to the sketch.
+// key : Name of sketch where items are added
+// items : Items to be added
+// Returns the items expelled from the list by every added item,
+// an empty string if nothing was expelled.
+func (t *TopK) Add(ctx context.Context, key string, items ...string) ([]string, error) {
+	args := []interface{}{"TOPK.ADD", key}
+	for _, item := range items {
+		args = append(args, item)
+	}
+	return t.expelled(ctx, args)
+}
+
+// Increment is an item and the number to increase its score by.
+type Increment struct {
+	Item string
+	By   int64
+}
+
+// IncrBy Increases the score of items by increments.
+// key : Name of sketch where items are added
+// increments : Items and the increments of their scores
+// Returns the items expelled from the list by every increased item,
+// an empty string if nothing was expelled.
+func (t *TopK) IncrBy(ctx context.Context, key string, increments ...Increment) ([]string, error) {
+	args := []interface{}{"TOPK.INCRBY", key}
+	for _, incr := range increments {
+		args = append(args, incr.Item, incr.By)
+	}
+	return t.expelled(ctx, args)
+}
+
+func (t *TopK) expelled(ctx context.Context, args []interface{}) ([]string, error) {
+	cmd := redis.NewSliceCmd(ctx, args...)
+	t.proc.Process(ctx, cmd)
+	vals, err := cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+	return toStrings(vals)
+}
+
+func toStrings(vals []interface{}) ([]string, error) {
+	items := make([]string, len(vals))
+	for i, v := range vals {
+		switch v := v.(type) {
+		case string:
+			items[i] = v
+		case nil:
+		default:
+			return nil, fmt.Errorf("topk: unexpected item %T", v)
+		}
+	}
+	return items, nil
+}
+
+// Query Checks whether items are one of the Top-K items.
+// key : Name of sketch where items are queried
+// items : Items to be queried
+func (t *TopK) Query(ctx context.Context, key string, items ...string) ([]bool, error) {
+	args := []interface{}{"TOPK.QUERY", key}
+	for _, item := range items {
+		args = append(args, item)
+	}
+	cmd := redis.NewBoolSliceCmd(ctx, args...)
+	t.proc.Process(ctx, cmd)
+	return cmd.Result()
+}
+
+// Count Returns the estimated counts of items.
+// key : Name of sketch where items are counted
+// items : Items to be counted
+func (t *TopK) Count(ctx context.Context, key string, items ...string) ([]int64, error) {
+	args := []interface{}{"TOPK.COUNT", key}
+	for _, item := range items {
+		args = append(args, item)
+	}
+	cmd := redis.NewIntSliceCmd(ctx, args...)
+	t.proc.Process(ctx, cmd)
+	return cmd.Result()
+}
+
+// List Returns the full list of items in the Top-K list.
+// key : Name of sketch where the list is returned
+func (t *TopK) List(ctx context.Context, key string) ([]string, error) {
+	cmd := redis.NewStringSliceCmd(ctx, "TOPK.LIST", key)
+	t.proc.Process(ctx, cmd)
+	return cmd.Result()
+}
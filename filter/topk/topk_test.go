@@ -0,0 +1,78 @@
+package topk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shima-park/tools/filter/internal/redistest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopK(t *testing.T) {
+	s, client := redistest.NewServer(t)
+	s.Reply("TOPK.RESERVE", func(args []string) interface{} { return redistest.Status("OK") })
+	s.Reply("TOPK.ADD", func(args []string) interface{} { return []interface{}{nil, "old"} })
+	s.Reply("TOPK.INCRBY", func(args []string) interface{} { return []interface{}{nil} })
+	s.Reply("TOPK.QUERY", func(args []string) interface{} { return []interface{}{int64(1), int64(0)} })
+	s.Reply("TOPK.COUNT", func(args []string) interface{} { return []interface{}{int64(4), int64(0)} })
+	s.Reply("TOPK.LIST", func(args []string) interface{} { return []interface{}{"a", "b"} })
+
+	tk := New(client)
+	ctx := context.Background()
+
+	assert.Nil(t, tk.Reserve(ctx, "k", 10, nil))
+	assert.Nil(t, tk.Reserve(ctx, "k", 10, &ReserveOptions{Width: 50}))
+
+	expelled, err := tk.Add(ctx, "k", "a", "b")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"", "old"}, expelled)
+
+	expelled, err = tk.IncrBy(ctx, "k", Increment{Item: "a", By: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{""}, expelled)
+
+	found, err := tk.Query(ctx, "k", "a", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, false}, found)
+
+	counts, err := tk.Count(ctx, "k", "a", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{4, 0}, counts)
+
+	list, err := tk.List(ctx, "k")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, list)
+
+	assert.Equal(t, [][]string{
+		{"TOPK.RESERVE", "k", "10"},
+		{"TOPK.RESERVE", "k", "10", "50", "7", "0.9"},
+		{"TOPK.ADD", "k", "a", "b"},
+		{"TOPK.INCRBY", "k", "a", "3"},
+		{"TOPK.QUERY", "k", "a", "c"},
+		{"TOPK.COUNT", "k", "a", "c"},
+		{"TOPK.LIST", "k"},
+	}, s.Calls())
+}
+
+func TestTopKError(t *testing.T) {
+	s, client := redistest.NewServer(t)
+	s.Reply("TOPK.ADD", func(args []string) interface{} { return errors.New("ERR TopK: key does not exist") })
+	s.Reply("TOPK.LIST", func(args []string) interface{} { return []interface{}{int64(1)} })
+
+	tk := New(client)
+	_, err := tk.Add(context.Background(), "k", "a")
+	assert.EqualError(t, err, "ERR TopK: key does not exist")
+
+	_, err = tk.IncrBy(context.Background(), "missing", Increment{Item: "a", By: 1})
+	assert.NotNil(t, err)
+}
+
+func TestToStrings(t *testing.T) {
+	items, err := toStrings([]interface{}{nil, "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"", "a"}, items)
+
+	_, err = toStrings([]interface{}{int64(1)})
+	assert.NotNil(t, err)
+}
@@ -5,6 +5,7 @@ go 1.14
 require (
 	github.com/360EntSecGroup-Skylar/excelize v1.4.1
 	github.com/Shopify/sarama v1.27.0
+	github.com/alicebob/miniredis/v2 v2.17.0
 	github.com/apache/thrift v0.13.0
 	github.com/go-redis/redis/v8 v8.3.1
 	github.com/lu4p/unipdf/v3 v3.7.1
@@ -0,0 +1,329 @@
+package ioutil
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrBareQuote  = errors.New("bare \" in non-quoted field")
+	ErrQuote      = errors.New("extraneous or missing \" in quoted field")
+	ErrFieldCount = errors.New("wrong number of fields")
+	ErrOptions    = errors.New("invalid delimiter, quote or comment")
+)
+
+// RecordError is the error of a record, Line and Column start from 1,
+// Column counts runes.
+type RecordError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("record on line %d, column %d: %v", e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("record on line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// RecordOptions are the options of ScanRecords, Delimiter, Quote and
+// Comment must differ from each other and must not be \r, \n or an invalid rune.
+type RecordOptions struct {
+	Delimiter rune // separator of the fields, default ','
+	Quote     rune // quote of the fields, default '"'
+	Comment   rune // lines beginning with Comment are skipped, 0 disables comments
+	// Header treats the first record as the column names of the others, the
+	// scanning stops after reporting the first record if it is malformed.
+	Header bool
+	// LazyQuotes allows a quote to appear in a non-quoted field
+	// and a non-doubled quote to appear in a quoted field.
+	LazyQuotes bool
+	// FieldsPerRecord is the number of fields of every record, it is set to
+	// the number of fields of the first record if it is 0, and no check is
+	// made if it is negative.
+	FieldsPerRecord int
+}
+
+// Record is a record of delimited fields, Line is the line the record
+// begins on. Fields may be partially parsed if Err is not nil.
+type Record struct {
+	Line   int
+	Fields []string
+	Header []string
+	Err    error
+}
+
+// Get returns the field of column, ok is false if there is no such column.
+func (r Record) Get(column string) (string, bool) {
+	for i, name := range r.Header {
+		if name == column && i < len(r.Fields) {
+			return r.Fields[i], true
+		}
+	}
+	return "", false
+}
+
+// Map returns the fields keyed by their column names.
+func (r Record) Map() map[string]string {
+	m := make(map[string]string, len(r.Header))
+	for i, name := range r.Header {
+		if i < len(r.Fields) {
+			m[name] = r.Fields[i]
+		}
+	}
+	return m
+}
+
+// ScanRecords parses the delimited records of r such as CSV or TSV. The
+// records are read one by one, a malformed record is reported by its Err
+// and the scanning goes on with the next line. The scanning stops after
+// the record with a read error. Invalid options are reported by the only
+// record with ErrOptions.
+func ScanRecords(ctx context.Context, r io.Reader, opts RecordOptions) chan Record {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+	}
+	if opts.Quote == 0 {
+		opts.Quote = '"'
+	}
+
+	closeReader := func() {
+		if closer, ok := r.(io.ReadCloser); ok {
+			closer.Close()
+		}
+	}
+	if !opts.valid() {
+		closeReader()
+		ch := make(chan Record, 1)
+		ch <- Record{Err: ErrOptions}
+		close(ch)
+		return ch
+	}
+
+	ch := make(chan Record)
+	go func() {
+		defer func() {
+			close(ch)
+			closeReader()
+		}()
+
+		p := &recordParser{r: bufio.NewReader(r), opts: opts}
+		var header []string
+		for {
+			record, ok := p.next()
+			if !ok {
+				return
+			}
+
+			if opts.Header && header == nil {
+				if record.Err == nil {
+					header = record.Fields
+					continue
+				}
+				// the records can not be keyed without the header
+				select {
+				case ch <- record:
+				case <-ctx.Done():
+				}
+				return
+			}
+			record.Header = header
+
+			select {
+			case ch <- record:
+				if record.Err != nil {
+					var re *RecordError
+					if !errors.As(record.Err, &re) {
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func (o RecordOptions) valid() bool {
+	if !validRecordRune(o.Delimiter) || !validRecordRune(o.Quote) || o.Delimiter == o.Quote {
+		return false
+	}
+	return o.Comment == 0 || validRecordRune(o.Comment) && o.Comment != o.Delimiter && o.Comment != o.Quote
+}
+
+func validRecordRune(r rune) bool {
+	return r != '\r' && r != '\n' && r != utf8.RuneError && utf8.ValidRune(r)
+}
+
+func ScanFileRecords(ctx context.Context, filepath string, opts RecordOptions) chan Record {
+	f, err := os.Open(filepath)
+	if err != nil {
+		ch := make(chan Record, 1)
+		ch <- Record{Err: err}
+		close(ch)
+		return ch
+	}
+	return ScanRecords(ctx, f, opts)
+}
+
+type recordParser struct {
+	r    *bufio.Reader
+	opts RecordOptions
+	line int
+	eof  bool
+}
+
+// readLine returns the next line without the line ending,
+// ok is false once r is drained.
+func (p *recordParser) readLine() (line string, ok bool, err error) {
+	if p.eof {
+		return "", false, nil
+	}
+
+	line, err = p.r.ReadString('\n')
+	if err == io.EOF {
+		p.eof = true
+		err = nil
+		if line == "" {
+			return "", false, nil
+		}
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	p.line++
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), true, nil
+}
+
+// next returns the next record, ok is false once r is drained.
+func (p *recordParser) next() (record Record, ok bool) {
+	var line string
+	for {
+		var err error
+		line, ok, err = p.readLine()
+		if err != nil {
+			return Record{Line: p.line + 1, Err: err}, true
+		}
+		if !ok {
+			return Record{}, false
+		}
+		// the empty lines and the comment lines are skipped
+		if line == "" || (p.opts.Comment != 0 && strings.HasPrefix(line, string(p.opts.Comment))) {
+			continue
+		}
+		break
+	}
+
+	record = Record{Line: p.line}
+	record.Fields, record.Err = p.parse(line)
+	if record.Err != nil {
+		return record, true
+	}
+
+	switch {
+	case p.opts.FieldsPerRecord == 0:
+		p.opts.FieldsPerRecord = len(record.Fields)
+	case p.opts.FieldsPerRecord > 0 && len(record.Fields) != p.opts.FieldsPerRecord:
+		record.Err = &RecordError{Line: record.Line, Err: ErrFieldCount}
+	}
+	return record, true
+}
+
+// parse parses the fields of a record beginning with line, the following
+// lines are read if a quoted field spans lines.
+func (p *recordParser) parse(line string) ([]string, error) {
+	var (
+		fields []string
+		delim  = string(p.opts.Delimiter)
+		quote  = string(p.opts.Quote)
+		col    = 1 // column of line[0] in the current line, in runes
+	)
+
+fieldLoop:
+	for {
+		if !strings.HasPrefix(line, quote) {
+			// non-quoted field
+			i := strings.Index(line, delim)
+			field := line
+			if i >= 0 {
+				field = line[:i]
+			}
+			if !p.opts.LazyQuotes {
+				if j := strings.Index(field, quote); j >= 0 {
+					return fields, &RecordError{Line: p.line, Column: col + utf8.RuneCountInString(field[:j]), Err: ErrBareQuote}
+				}
+			}
+			fields = append(fields, field)
+			if i < 0 {
+				return fields, nil
+			}
+			col += utf8.RuneCountInString(line[:i]) + 1
+			line = line[i+len(delim):]
+			continue
+		}
+
+		// quoted field
+		var field strings.Builder
+		line = line[len(quote):]
+		col++
+		for {
+			i := strings.Index(line, quote)
+			if i < 0 {
+				// the field continues on the next line
+				field.WriteString(line)
+				next, ok, err := p.readLine()
+				if err != nil {
+					return fields, err
+				}
+				if !ok {
+					if !p.opts.LazyQuotes {
+						return fields, &RecordError{Line: p.line, Column: col + utf8.RuneCountInString(line), Err: ErrQuote}
+					}
+					return append(fields, field.String()), nil
+				}
+				field.WriteByte('\n')
+				line, col = next, 1
+				continue
+			}
+
+			field.WriteString(line[:i])
+			rest := line[i+len(quote):]
+			switch {
+			case strings.HasPrefix(rest, quote):
+				// doubled quote
+				field.WriteString(quote)
+				col += utf8.RuneCountInString(line[:i]) + 2
+				line = rest[len(quote):]
+			case rest == "":
+				return append(fields, field.String()), nil
+			case strings.HasPrefix(rest, delim):
+				fields = append(fields, field.String())
+				col += utf8.RuneCountInString(line[:i]) + 2
+				line = rest[len(delim):]
+				continue fieldLoop
+			case p.opts.LazyQuotes:
+				field.WriteString(quote)
+				col += utf8.RuneCountInString(line[:i]) + 1
+				line = rest
+			default:
+				return fields, &RecordError{Line: p.line, Column: col + utf8.RuneCountInString(line[:i]), Err: ErrQuote}
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package ioutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scanAll(input string, opts RecordOptions) []Record {
+	var records []Record
+	for record := range ScanRecords(context.Background(), strings.NewReader(input), opts) {
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestScanRecords(t *testing.T) {
+	input := "name,comment\r\n" +
+		"# skipped\n" +
+		"a,\"x, y\"\n" +
+		"\n" +
+		"b,\"say \"\"hi\"\"\nbye\"\n" +
+		"c,"
+	records := scanAll(input, RecordOptions{Header: true, Comment: '#'})
+
+	assert.Equal(t, 3, len(records))
+	for _, record := range records {
+		assert.Nil(t, record.Err)
+		assert.Equal(t, []string{"name", "comment"}, record.Header)
+	}
+	assert.Equal(t, Record{Line: 3, Fields: []string{"a", "x, y"}, Header: []string{"name", "comment"}}, records[0])
+	assert.Equal(t, 5, records[1].Line)
+	assert.Equal(t, map[string]string{"name": "b", "comment": "say \"hi\"\nbye"}, records[1].Map())
+	assert.Equal(t, 7, records[2].Line)
+	v, ok := records[2].Get("comment")
+	assert.True(t, ok)
+	assert.Equal(t, "", v)
+	_, ok = records[2].Get("missing")
+	assert.False(t, ok)
+}
+
+func TestScanRecordsErrors(t *testing.T) {
+	input := "a\tb\n" +
+		"c\td\"\n" +
+		"\"e\"x\tf\n" +
+		"g\n" +
+		"h\ti\n" +
+		"\"j\tk\n"
+	records := scanAll(input, RecordOptions{Delimiter: '\t'})
+
+	assert.Equal(t, 6, len(records))
+	assert.Equal(t, []string{"a", "b"}, records[0].Fields)
+
+	var re *RecordError
+	assert.True(t, errors.As(records[1].Err, &re))
+	assert.Equal(t, &RecordError{Line: 2, Column: 4, Err: ErrBareQuote}, re)
+	assert.True(t, errors.As(records[2].Err, &re))
+	assert.Equal(t, &RecordError{Line: 3, Column: 3, Err: ErrQuote}, re)
+	assert.True(t, errors.Is(records[3].Err, ErrFieldCount))
+	assert.Equal(t, []string{"g"}, records[3].Fields)
+	assert.Nil(t, records[4].Err)
+	assert.True(t, errors.Is(records[5].Err, ErrQuote))
+	assert.Equal(t, "record on line 6, column 5: extraneous or missing \" in quoted field", records[5].Err.Error())
+
+	records = scanAll("a\"b;\"c\"d\"\n", RecordOptions{Delimiter: ';', LazyQuotes: true})
+	assert.Equal(t, 1, len(records))
+	assert.Nil(t, records[0].Err)
+	assert.Equal(t, []string{"a\"b", "c\"d"}, records[0].Fields)
+
+	// the last line ending without a newline
+	records = scanAll("a,b\r", RecordOptions{})
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, []string{"a", "b"}, records[0].Fields)
+}
+
+func TestScanRecordsRuneColumns(t *testing.T) {
+	records := scanAll("日本,語\"x\n\"é\"\"ü\"x;\n", RecordOptions{FieldsPerRecord: -1})
+
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, &RecordError{Line: 1, Column: 5, Err: ErrBareQuote}, records[0].Err)
+	assert.Equal(t, &RecordError{Line: 2, Column: 6, Err: ErrQuote}, records[1].Err)
+
+	records = scanAll("ä|b|\"ö\"|\"c\n", RecordOptions{Delimiter: '|'})
+	assert.Equal(t, &RecordError{Line: 1, Column: 11, Err: ErrQuote}, records[0].Err)
+}
+
+func TestScanRecordsOptions(t *testing.T) {
+	for _, opts := range []RecordOptions{
+		{Delimiter: '"'},
+		{Quote: ','},
+		{Delimiter: ';', Quote: ';'},
+		{Delimiter: '\n'},
+		{Delimiter: '\r'},
+		{Quote: '\n'},
+		{Delimiter: utf8.RuneError},
+		{Delimiter: -1},
+		{Quote: 0x110000},
+		{Comment: ','},
+		{Comment: '"'},
+		{Comment: '\n'},
+		{Comment: utf8.RuneError},
+	} {
+		records := scanAll("a,b\n", opts)
+		assert.Equal(t, []Record{{Err: ErrOptions}}, records, "%+v", opts)
+	}
+
+	records := scanAll("#a;b\n'c;d';e\n", RecordOptions{Delimiter: ';', Quote: '\'', Comment: '#'})
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, []string{"c;d", "e"}, records[0].Fields)
+}
+
+func TestScanRecordsBadHeader(t *testing.T) {
+	records := scanAll("\"bad\"x,1\nname,v\n1,2\n", RecordOptions{Header: true})
+
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, 1, records[0].Line)
+	assert.True(t, errors.Is(records[0].Err, ErrQuote))
+}
+
+func TestScanRecordsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := ScanRecords(ctx, strings.NewReader(strings.Repeat("a,b\n", 100)), RecordOptions{})
+	<-ch
+	cancel()
+	for range ch {
+	}
+}
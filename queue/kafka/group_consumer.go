@@ -3,8 +3,10 @@ package kafka
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/Shopify/sarama"
+	"github.com/shima-park/tools/concurrent/retry"
 	"go.uber.org/atomic"
 )
 
@@ -22,6 +24,9 @@ type GroupConsumerConfig struct {
 	Topics        []string
 	ConsumerGroup string
 	Config        *sarama.Config
+	// Retry is used to create the consumer group and to back off after
+	// consuming failed, nil means no retry and no backoff.
+	Retry *retry.Policy
 }
 
 func NewGroupConsumer(config GroupConsumerConfig) *GroupConsumer {
@@ -47,7 +52,12 @@ func (c *GroupConsumer) Start(pctx context.Context, errHandle func(error), handl
 	}
 	c.ctx, c.cancel = context.WithCancel(pctx)
 
-	group, err := sarama.NewConsumerGroup(c.config.Addrs, c.config.ConsumerGroup, c.config.Config)
+	var group sarama.ConsumerGroup
+	err := c.retry(c.ctx, func(ctx context.Context) error {
+		var err error
+		group, err = sarama.NewConsumerGroup(c.config.Addrs, c.config.ConsumerGroup, c.config.Config)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -61,6 +71,7 @@ func (c *GroupConsumer) Start(pctx context.Context, errHandle func(error), handl
 		}
 	}()
 
+	var failures int
 	for !c.isClosed.Load() {
 		select {
 		case <-c.ctx.Done():
@@ -71,15 +82,43 @@ func (c *GroupConsumer) Start(pctx context.Context, errHandle func(error), handl
 			}
 
 			err := group.Consume(c.ctx, c.config.Topics, handler)
-			if err != nil && errHandle != nil {
+			if err == nil {
+				failures = 0
+				continue
+			}
+
+			if errHandle != nil {
 				errHandle(err)
 			}
+			failures++
+			c.backoff(failures)
 		}
 	}
 
 	return nil
 }
 
+func (c *GroupConsumer) retry(ctx context.Context, fn func(ctx context.Context) error) error {
+	if c.config.Retry == nil {
+		return fn(ctx)
+	}
+	return retry.Do(ctx, *c.config.Retry, fn)
+}
+
+func (c *GroupConsumer) backoff(failures int) {
+	if c.config.Retry == nil {
+		return
+	}
+
+	timer := time.NewTimer(c.config.Retry.Backoff(failures))
+	defer timer.Stop()
+
+	select {
+	case <-c.ctx.Done():
+	case <-timer.C:
+	}
+}
+
 func (c *GroupConsumer) Stop() {
 	if !c.isClosed.CAS(false, true) {
 		return
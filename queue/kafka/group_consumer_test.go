@@ -0,0 +1,57 @@
+package kafka
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/shima-park/tools/concurrent/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+// brokenBroker accepts the connections and closes them at once.
+func brokenBroker(t *testing.T) (addr string, conns *int32, close func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conns = new(int32)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(conns, 1)
+			conn.Close()
+		}
+	}()
+	return l.Addr().String(), conns, func() { l.Close() }
+}
+
+func TestGroupConsumerRetry(t *testing.T) {
+	addr, conns, closeBroker := brokenBroker(t)
+	defer closeBroker()
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	config.Metadata.Retry.Max = 0
+
+	policy := retry.Constant(time.Millisecond, 3)
+	c := NewGroupConsumer(GroupConsumerConfig{
+		Addrs:         []string{addr},
+		Topics:        []string{"topic"},
+		ConsumerGroup: "group",
+		Config:        config,
+		Retry:         &policy,
+	})
+
+	err := c.Start(context.Background(), nil, func(msg *sarama.ConsumerMessage) (bool, bool) { return true, true })
+	assert.NotNil(t, err)
+	// every attempt of creating the consumer group connects to the broker
+	assert.Equal(t, int32(3), atomic.LoadInt32(conns))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/Shopify/sarama"
+	"github.com/shima-park/tools/concurrent/retry"
 	"go.uber.org/atomic"
 	"sync"
 )
@@ -21,6 +22,8 @@ type PartitionConsumerConfig struct {
 	Topic      string
 	Partitions []int32
 	Offset     int64 // 0~n, OffsetOldest, OffsetNewest
+	// Retry is used to create the consumer and the partition consumers, nil means no retry.
+	Retry *retry.Policy
 }
 
 func NewPartitionConsumer(config PartitionConsumerConfig) *PartitionConsumer {
@@ -45,7 +48,12 @@ func (c *PartitionConsumer) Start(pctx context.Context, handle PartitionConsumer
 		return errors.New("The PartitionConsumerHandler cannot be nil")
 	}
 
-	master, err := sarama.NewConsumer(c.config.Addrs, nil)
+	var master sarama.Consumer
+	err := c.retry(c.ctx, func(ctx context.Context) error {
+		var err error
+		master, err = sarama.NewConsumer(c.config.Addrs, nil)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -60,8 +68,13 @@ func (c *PartitionConsumer) Start(pctx context.Context, handle PartitionConsumer
 
 	var consumers []sarama.PartitionConsumer
 	for _, partition := range partitions {
-		consumer, err := master.ConsumePartition(
-			c.config.Topic, partition, c.config.Offset)
+		var consumer sarama.PartitionConsumer
+		err := c.retry(c.ctx, func(ctx context.Context) error {
+			var err error
+			consumer, err = master.ConsumePartition(
+				c.config.Topic, partition, c.config.Offset)
+			return err
+		})
 		if err != nil {
 			return err
 		}
@@ -95,6 +108,13 @@ func (c *PartitionConsumer) Start(pctx context.Context, handle PartitionConsumer
 	return nil
 }
 
+func (c *PartitionConsumer) retry(ctx context.Context, fn func(ctx context.Context) error) error {
+	if c.config.Retry == nil {
+		return fn(ctx)
+	}
+	return retry.Do(ctx, *c.config.Retry, fn)
+}
+
 func (c *PartitionConsumer) Stop() {
 	if !c.isClosed.CAS(false, true) {
 		return
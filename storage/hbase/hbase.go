@@ -9,13 +9,27 @@ import (
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/shima-park/tools/concurrent/retry"
 	"github.com/shima-park/tools/storage/hbase/gen-go/hbase"
 )
 
+// DefaultReconnectPolicy reconnects 30 times at an interval of one second.
+func DefaultReconnectPolicy() retry.Policy {
+	return retry.Constant(time.Second, 30)
+}
+
+// DefaultScanRetryPolicy reopens the failed scanners every second until
+// the scan is canceled.
+func DefaultScanRetryPolicy() retry.Policy {
+	return retry.Constant(time.Second, 0)
+}
+
 type HBaseClient struct {
-	lock   *sync.Mutex
-	addr   string
-	closer func() error
+	lock      *sync.Mutex
+	addr      string
+	closer    func() error
+	reconnect retry.Policy
+	scanRetry retry.Policy
 	*hbase.HbaseClient
 }
 
@@ -29,10 +43,47 @@ func NewHBaseClient(addr string) (*HBaseClient, error) {
 		lock:        &sync.Mutex{},
 		addr:        addr,
 		closer:      closer,
+		reconnect:   DefaultReconnectPolicy(),
+		scanRetry:   DefaultScanRetryPolicy(),
 		HbaseClient: client,
 	}, nil
 }
 
+// SetReconnectPolicy sets the policy of reconnecting when the connection is broken.
+func (c *HBaseClient) SetReconnectPolicy(p retry.Policy) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.reconnect = p
+}
+
+// SetScanRetryPolicy sets the policy of reopening the scanner when a scan
+// failed, the attempts are counted over the whole scan.
+func (c *HBaseClient) SetScanRetryPolicy(p retry.Policy) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.scanRetry = p
+}
+
+func (c *HBaseClient) policies() (reconnect, scanRetry retry.Policy) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.reconnect, c.scanRetry
+}
+
+// reconnectOnce replaces the broken connection with a new one.
+func (c *HBaseClient) reconnectOnce() error {
+	client, closer, err := newHBaseClient(c.addr)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	c.closer()
+	c.HbaseClient = client
+	c.closer = closer
+	c.lock.Unlock()
+	return nil
+}
+
 func newHBaseClient(addr string) (*hbase.HbaseClient, func() error, error) {
 	trans, err := thrift.NewTSocket(addr)
 	if err != nil {
@@ -93,48 +144,65 @@ func (c *HBaseClient) Scan(ctx context.Context, table, start, end string,
 			Columns:  columns,
 		}
 
-		for {
-			scannerID, err := c.ScannerOpenWithScan(ctx, []byte(table), scan, attributes)
-			if err != nil {
-				if err == io.EOF || errors.Is(err, syscall.EPIPE) {
-					for retries := 0; retries < 30; retries++ {
-						client, closer, err := newHBaseClient(c.addr)
-						if err != nil {
-							time.Sleep(time.Second)
-							continue
-						}
-						c.lock.Lock()
-						c.closer()
-						c.HbaseClient = client
-						c.closer = closer
-						c.lock.Unlock()
-						break
-					}
-				}
-				errHandle(err)
-				continue
-			}
+		reconnect, scanRetry := c.policies()
+		retryScan(ctx, scanRetry, reconnect, func(ctx context.Context) error {
+			return c.scanOnce(ctx, table, scan, attributes, resultHandle)
+		}, c.reconnectOnce, errHandle)
+	}()
+	return ch
+}
 
-			for {
-				results, err := c.ScannerGetList(ctx, scannerID, 1000)
-				if err != nil {
-					errHandle(err)
-					scan.StartRow = createClosestRowAfter(scan.StartRow)
-					c.ScannerClose(context.Background(), scannerID) //不传入当前上下文，防止未Close就退出了
-					break
-				}
-
-				if len(results) > 0 {
-					resultHandle(results)
-					scan.StartRow = results[len(results)-1].Row
-				} else {
-					return
-				}
+// retryScan calls scan until it finished or ctx is done, the failures are
+// passed to errHandle once each. The scan is retried by scanRetry, and the
+// broken connection is reconnected by reconnect before the scan is retried.
+// It gives up once either policy gives up.
+func retryScan(ctx context.Context, scanRetry, reconnect retry.Policy,
+	scan func(ctx context.Context) error, reconnectOnce func() error, errHandle func(error)) {
+
+	_ = retry.Do(ctx, scanRetry, func(ctx context.Context) error {
+		err := scan(ctx)
+		if err == nil || ctx.Err() != nil {
+			return retry.Permanent(err)
+		}
+		errHandle(err)
+
+		if err == io.EOF || errors.Is(err, syscall.EPIPE) {
+			rerr := retry.Do(ctx, reconnect, func(ctx context.Context) error {
+				return reconnectOnce()
+			})
+			if rerr != nil {
+				errHandle(rerr)
+				return retry.Permanent(rerr)
 			}
 		}
+		return err
+	})
+}
 
-	}()
-	return ch
+// scanOnce opens a scanner from scan.StartRow and passes the results to
+// handle until the scan finished or failed, scan.StartRow is moved after
+// the last row received so the scan can be resumed.
+func (c *HBaseClient) scanOnce(ctx context.Context, table string, scan *hbase.TScan,
+	attributes map[string]hbase.Text, handle func([]*hbase.TRowResult_)) error {
+
+	scannerID, err := c.ScannerOpenWithScan(ctx, []byte(table), scan, attributes)
+	if err != nil {
+		return err
+	}
+	defer c.ScannerClose(context.Background(), scannerID) //不传入当前上下文，防止未Close就退出了
+
+	for {
+		results, err := c.ScannerGetList(ctx, scannerID, 1000)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return nil
+		}
+
+		handle(results)
+		scan.StartRow = createClosestRowAfter(results[len(results)-1].Row)
+	}
 }
 
 func createClosestRowAfter(row []byte) []byte {
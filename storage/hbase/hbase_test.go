@@ -0,0 +1,77 @@
+package hbase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/shima-park/tools/concurrent/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryScan(t *testing.T) {
+	ctx := context.Background()
+	policy := retry.Constant(time.Millisecond, 0)
+	timeout := errors.New("timeout")
+
+	var errs []error
+	errHandle := func(err error) { errs = append(errs, err) }
+
+	// the failed scans are retried until the scan finished
+	var scans, reconnects int
+	retryScan(ctx, policy, policy, func(ctx context.Context) error {
+		scans++
+		switch scans {
+		case 1:
+			return timeout
+		case 2:
+			return io.EOF
+		}
+		return nil
+	}, func() error {
+		reconnects++
+		return nil
+	}, errHandle)
+	assert.Equal(t, 3, scans)
+	assert.Equal(t, 1, reconnects)
+	assert.Equal(t, []error{timeout, io.EOF}, errs)
+
+	// the scan gives up once reconnecting gave up
+	refused := errors.New("connection refused")
+	scans, reconnects, errs = 0, 0, nil
+	retryScan(ctx, policy, retry.Constant(time.Millisecond, 2), func(ctx context.Context) error {
+		scans++
+		return io.EOF
+	}, func() error {
+		reconnects++
+		return refused
+	}, errHandle)
+	assert.Equal(t, 1, scans)
+	assert.Equal(t, 2, reconnects)
+	assert.Equal(t, []error{io.EOF, refused}, errs)
+
+	// the scan gives up once the scan retry policy gave up
+	scans, errs = 0, nil
+	retryScan(ctx, retry.Constant(time.Millisecond, 3), policy, func(ctx context.Context) error {
+		scans++
+		return timeout
+	}, nil, errHandle)
+	assert.Equal(t, 3, scans)
+	assert.Equal(t, []error{timeout, timeout, timeout}, errs)
+}
+
+func TestRetryScanCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var scans int
+	retryScan(ctx, DefaultScanRetryPolicy(), DefaultReconnectPolicy(), func(ctx context.Context) error {
+		scans++
+		cancel()
+		return ctx.Err()
+	}, nil, func(err error) {
+		t.Fatalf("unexpected error %v", err)
+	})
+	assert.Equal(t, 1, scans)
+}